@@ -1,20 +1,24 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 	"github.com/thepsadmin/mathemcli/internal/api"
 )
 
+var cartGroupBy string
+
 var cartCmd = &cobra.Command{
 	Use:   "cart",
 	Short: "Manage shopping cart",
 	Long:  `View and manage your Mathem shopping cart.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		// Default action: show cart
-		return showCart()
+		return showCart(cmd.Context())
 	},
 }
 
@@ -22,7 +26,7 @@ var cartShowCmd = &cobra.Command{
 	Use:   "show",
 	Short: "Show cart contents",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return showCart()
+		return showCart(cmd.Context())
 	},
 }
 
@@ -49,24 +53,87 @@ var cartAddCmd = &cobra.Command{
 			{ProductID: productID, Quantity: quantity},
 		}
 
-		cart, err := client.AddToCart(items)
+		cart, err := client.AddToCart(cmd.Context(), items)
 		if err != nil {
 			return fmt.Errorf("failed to add to cart: %w", err)
 		}
 
 		fmt.Printf("Added %d item(s) to cart\n", quantity)
-		fmt.Printf("Cart total: %s %s (%d items)\n",
-			cart.DisplayPrice, cart.Currency, cart.ProductQuantityCount)
+		fmt.Printf("Cart total: %s (%d items)\n",
+			cart.DisplayPrice, cart.ProductQuantityCount)
 
 		return nil
 	},
 }
 
+var cartSetCmd = &cobra.Command{
+	Use:   "set [product_id] [quantity]",
+	Short: "Set the quantity of a product in the cart",
+	Long:  `Set the quantity of a product in the cart. A quantity of 0 removes it.`,
+	Args:  cobra.ExactArgs(2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		productID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid product ID: %w", err)
+		}
+
+		quantity, err := strconv.Atoi(args[1])
+		if err != nil || quantity < 0 {
+			return fmt.Errorf("invalid quantity %q", args[1])
+		}
+
+		return updateQuantities(cmd.Context(), []int{productID}, func(int) int {
+			return quantity
+		})
+	},
+}
+
+var cartRemoveCmd = &cobra.Command{
+	Use:   "remove [product_id]...",
+	Short: "Remove products from cart",
+	Args:  cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		productIDs, err := parseProductIDs(args)
+		if err != nil {
+			return err
+		}
+
+		return updateQuantities(cmd.Context(), productIDs, func(int) int {
+			return 0
+		})
+	},
+}
+
+var cartDecCmd = &cobra.Command{
+	Use:   "dec [product_id] [n]",
+	Short: "Decrease the quantity of a product in the cart",
+	Long:  `Decrease the quantity of a product in the cart by n (default 1).`,
+	Args:  cobra.RangeArgs(1, 2),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		productID, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid product ID: %w", err)
+		}
+
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid amount %q", args[1])
+			}
+		}
+
+		return updateQuantities(cmd.Context(), []int{productID}, func(before int) int {
+			return max(before-n, 0)
+		})
+	},
+}
+
 var cartClearCmd = &cobra.Command{
 	Use:   "clear",
 	Short: "Clear all items from cart",
 	RunE: func(cmd *cobra.Command, args []string) error {
-		_, err := client.ClearCart()
+		_, err := client.ClearCart(cmd.Context())
 		if err != nil {
 			return fmt.Errorf("failed to clear cart: %w", err)
 		}
@@ -76,8 +143,111 @@ var cartClearCmd = &cobra.Command{
 	},
 }
 
-func showCart() error {
-	cart, err := client.GetCart()
+// parseProductIDs parses product ID arguments
+func parseProductIDs(args []string) ([]int, error) {
+	ids := make([]int, 0, len(args))
+	for _, arg := range args {
+		id, err := strconv.Atoi(arg)
+		if err != nil {
+			return nil, fmt.Errorf("invalid product ID: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// updateQuantities sets the quantity of each product to target(current).
+// Since the API only adds to quantities, the deltas against the current
+// cart are sent in one request. Products that are not in the cart and
+// would stay at zero are reported and skipped.
+//
+// Decreasing relies on the API accepting negative deltas, which has not
+// been confirmed against mathem.se, so the returned cart is checked and
+// any product that didn't end up at its target is reported as an error.
+func updateQuantities(ctx context.Context, productIDs []int, target func(before int) int) error {
+	cart, err := client.GetCart(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get cart: %w", err)
+	}
+
+	var items []api.CartItem
+	before := make(map[int]int)
+	want := make(map[int]int)
+	for _, id := range productIDs {
+		if _, seen := before[id]; seen {
+			continue
+		}
+		before[id] = cart.Quantity(id)
+		want[id] = target(before[id])
+
+		if delta := want[id] - before[id]; delta != 0 {
+			items = append(items, api.CartItem{ProductID: id, Quantity: delta})
+		}
+	}
+
+	after := cart
+	if len(items) > 0 {
+		after, err = client.AddToCart(ctx, items)
+		if err != nil {
+			return fmt.Errorf("failed to update cart: %w", err)
+		}
+	}
+
+	for _, id := range productIDs {
+		qty, ok := before[id]
+		if !ok {
+			continue
+		}
+		delete(before, id)
+
+		item, inCart := cart.Item(id)
+		if !inCart {
+			item, inCart = after.Item(id)
+		}
+		if !inCart {
+			fmt.Printf("[%d] is not in the cart\n", id)
+			continue
+		}
+		fmt.Printf("[%d] %s: %d → %d\n", id, item.Product.FullName, qty, after.Quantity(id))
+	}
+
+	fmt.Printf("Cart total: %s (%d items)\n",
+		after.DisplayPrice, after.ProductQuantityCount)
+
+	var mismatched []string
+	for _, item := range items {
+		if got := after.Quantity(item.ProductID); got != want[item.ProductID] {
+			mismatched = append(mismatched, fmt.Sprintf("[%d] is %d, not %d", item.ProductID, got, want[item.ProductID]))
+		}
+	}
+	if len(mismatched) > 0 {
+		return fmt.Errorf("the cart did not take the new quantities: %s. Check it with 'mathemcli cart'",
+			strings.Join(mismatched, ", "))
+	}
+
+	return nil
+}
+
+// cartGrouping parses the --group-by flag
+func cartGrouping() (api.CartGrouping, error) {
+	switch cartGroupBy {
+	case "recipes":
+		return api.GroupByRecipes, nil
+	case "categories":
+		return api.GroupByCategories, nil
+	case "none":
+		return api.GroupByNone, nil
+	}
+	return "", fmt.Errorf("invalid --group-by %q, use categories, recipes or none", cartGroupBy)
+}
+
+func showCart(ctx context.Context) error {
+	groupBy, err := cartGrouping()
+	if err != nil {
+		return err
+	}
+
+	cart, err := client.GetCartGrouped(ctx, groupBy)
 	if err != nil {
 		return fmt.Errorf("failed to get cart: %w", err)
 	}
@@ -90,18 +260,30 @@ func showCart() error {
 	fmt.Printf("Cart: %s (%d items)\n\n", cart.LabelText, cart.ProductQuantityCount)
 
 	for _, group := range cart.Groups {
+		// Unnamed groups, like items outside any recipe, get no heading
+		if group.Name != "" {
+			fmt.Printf("── %s ──\n\n", group.Name)
+		}
+
+		subtotal := group.Subtotal
 		for _, item := range group.Items {
 			fmt.Printf("[%d] %s\n", item.Product.ID, item.Product.FullName)
 			if item.Product.NameExtra != "" {
 				fmt.Printf("     %s\n", item.Product.NameExtra)
 			}
-			fmt.Printf("     Qty: %d × %s %s = %s %s\n",
+			fmt.Printf("     Qty: %d × %s = %s\n",
 				item.Quantity,
 				item.Product.GrossPrice,
-				cart.Currency,
-				item.DisplayPrice,
-				cart.Currency)
+				item.DisplayPrice)
 			fmt.Println()
+
+			if group.Subtotal.IsZero() {
+				subtotal = subtotal.Add(item.DisplayPrice)
+			}
+		}
+
+		if group.Name != "" {
+			fmt.Printf("%-25s %12s\n\n", group.Name+" total", subtotal)
 		}
 	}
 
@@ -109,15 +291,26 @@ func showCart() error {
 	fmt.Println("─────────────────────────────────")
 	for _, summary := range cart.SummaryLines {
 		for _, line := range summary.Lines {
-			fmt.Printf("%-25s %s %s\n", line.Description, line.GrossAmount, cart.Currency)
+			fmt.Printf("%-25s %12s\n", line.Description, line.GrossAmount)
 		}
 	}
 
+	printCartPerks(ctx, cart)
+
 	return nil
 }
 
 func init() {
+	// Only the commands that show the cart take --group-by
+	for _, cmd := range []*cobra.Command{cartCmd, cartShowCmd} {
+		cmd.Flags().StringVarP(&cartGroupBy, "group-by", "g", "recipes", "Group cart items by categories, recipes or none")
+	}
+
 	cartCmd.AddCommand(cartShowCmd)
 	cartCmd.AddCommand(cartAddCmd)
+	cartCmd.AddCommand(cartSetCmd)
+	cartCmd.AddCommand(cartRemoveCmd)
+	cartCmd.AddCommand(cartDecCmd)
 	cartCmd.AddCommand(cartClearCmd)
+	cartCmd.AddCommand(cartValidateCmd)
 }
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+// Exit codes of 'cart validate'. When the cart has several kinds of
+// problems, the lowest code wins.
+const (
+	exitUnavailable   = 2
+	exitQuantityLimit = 3
+	exitMinimumOrder  = 4
+	exitOtherProblem  = 5
+)
+
+var validateJSON bool
+
+var cartValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check that the cart can be ordered",
+	Long: `Check the cart for unavailable products, quantity limits, a total
+below the minimum order and other problems that block checkout.
+
+Exit codes:
+  0  the cart is ready to order
+  1  the check itself failed
+  2  products are unavailable
+  3  quantity limits are exceeded
+  4  the minimum order is not reached
+  5  other problems, e.g. no delivery slot reserved
+
+When there are several kinds of problems, the lowest code is used.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		validation, err := client.ValidateCart(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to validate cart: %w", err)
+		}
+
+		if validateJSON {
+			enc := json.NewEncoder(os.Stdout)
+			enc.SetIndent("", "  ")
+			if err := enc.Encode(validation); err != nil {
+				return err
+			}
+		} else {
+			printValidation(validation)
+		}
+
+		return validationError(validation)
+	},
+}
+
+// validationError returns an exitError for the most important problem
+// class, or nil if the cart is ready
+func validationError(v *api.CartValidation) error {
+	var code int
+	switch {
+	case len(v.UnavailableProducts) > 0:
+		code = exitUnavailable
+	case len(v.QuantityLimits) > 0:
+		code = exitQuantityLimit
+	case v.MinimumOrder != nil:
+		code = exitMinimumOrder
+	case len(v.Errors) > 0 || !v.IsValid:
+		code = exitOtherProblem
+	default:
+		return nil
+	}
+
+	return &exitError{code: code, err: errors.New("cart is not ready to order")}
+}
+
+// printValidation lists the problems found, one section per class
+func printValidation(v *api.CartValidation) {
+	if len(v.UnavailableProducts) == 0 && len(v.QuantityLimits) == 0 &&
+		v.MinimumOrder == nil && len(v.Errors) == 0 {
+		if v.IsValid {
+			fmt.Println("Cart is ready to order")
+		} else {
+			fmt.Println("Cart can't be ordered, but no reason was given")
+		}
+		return
+	}
+
+	// heading separates sections with a blank line
+	first := true
+	heading := func(title string) {
+		if !first {
+			fmt.Println()
+		}
+		first = false
+		fmt.Println(title)
+	}
+
+	if len(v.UnavailableProducts) > 0 {
+		heading("Unavailable products:")
+		for _, p := range v.UnavailableProducts {
+			fmt.Printf("  [%d] %s", p.ProductID, p.FullName)
+			if p.Reason != "" {
+				fmt.Printf(" (%s)", p.Reason)
+			}
+			fmt.Println()
+		}
+	}
+
+	if len(v.QuantityLimits) > 0 {
+		heading("Quantity limits exceeded:")
+		for _, q := range v.QuantityLimits {
+			fmt.Printf("  [%d] %s: %d in cart, max %d\n", q.ProductID, q.FullName, q.Quantity, q.MaxQuantity)
+		}
+	}
+
+	if m := v.MinimumOrder; m != nil {
+		heading("Minimum order not reached:")
+		fmt.Printf("  %s of %s, add %s more\n", m.CurrentAmount, m.MinimumAmount, m.Shortfall())
+	}
+
+	if len(v.Errors) > 0 {
+		heading("Other problems:")
+		for _, e := range v.Errors {
+			fmt.Printf("  %s (%s)\n", e.Message, e.Code)
+		}
+	}
+}
+
+func init() {
+	cartValidateCmd.Flags().BoolVar(&validateJSON, "json", false, "Print the problems as JSON")
+}
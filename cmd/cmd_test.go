@@ -0,0 +1,1227 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/thepsadmin/mathemcli/internal/api"
+	"github.com/thepsadmin/mathemcli/internal/config"
+	"github.com/thepsadmin/mathemcli/internal/mathemtest"
+	"github.com/thepsadmin/mathemcli/internal/transport"
+)
+
+// newTestServer starts a fake Mathem server and points the CLI at it, with
+// a home directory of its own for the session file
+func newTestServer(t *testing.T) *mathemtest.Server {
+	t.Helper()
+
+	srv := mathemtest.NewServer()
+	t.Cleanup(srv.Close)
+
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("USERPROFILE", os.Getenv("HOME"))
+	t.Setenv("MATHEMCLI_API_URL", srv.APIURL())
+	t.Setenv("MATHEMCLI_WEB_URL", srv.URL)
+
+	return srv
+}
+
+// loginTestSession saves a session for the fake server's default user
+func loginTestSession(t *testing.T, srv *mathemtest.Server) {
+	t.Helper()
+
+	session := &config.Session{
+		SessionID: srv.NewSession(),
+		Email:     mathemtest.Email,
+	}
+	if err := config.SaveSession(session); err != nil {
+		t.Fatalf("SaveSession: %v", err)
+	}
+}
+
+// runCLI executes the root command with args and returns what it printed
+// to stdout
+func runCLI(t *testing.T, args ...string) (string, error) {
+	t.Helper()
+
+	resetFlags(rootCmd)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+
+	var out bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&out, r)
+		close(done)
+	}()
+
+	rootCmd.SetArgs(args)
+	runErr := rootCmd.Execute()
+	cancelTimeout()
+
+	w.Close()
+	<-done
+	return out.String(), runErr
+}
+
+// resetFlags restores every flag to its default and clears the context,
+// since cobra keeps both between executions of the same command tree
+func resetFlags(cmd *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		// Setting a slice flag appends to it, and its default prints as "[]"
+		if slice, ok := f.Value.(pflag.SliceValue); ok {
+			slice.Replace(nil)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+	cmd.Flags().VisitAll(reset)
+	cmd.PersistentFlags().VisitAll(reset)
+
+	// Cobra only gives a command the new context if it has none, so a
+	// --timeout deadline would otherwise outlive the execution
+	cmd.SetContext(nil)
+
+	for _, sub := range cmd.Commands() {
+		resetFlags(sub)
+	}
+}
+
+func assertContains(t *testing.T, out string, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		if !strings.Contains(out, w) {
+			t.Errorf("output does not contain %q:\n%s", w, out)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	newTestServer(t)
+
+	out, err := runCLI(t, "login", "-e", mathemtest.Email, "-p", mathemtest.Password)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	assertContains(t, out, "Successfully logged in as "+mathemtest.Email)
+
+	session, err := config.LoadSession()
+	if err != nil || session == nil || session.SessionID == "" {
+		t.Fatalf("session not saved: %+v, %v", session, err)
+	}
+}
+
+// TestLoginTimeoutAfterPrompt checks that time spent typing at the prompt
+// doesn't count towards --timeout
+func TestLoginTimeoutAfterPrompt(t *testing.T) {
+	newTestServer(t)
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	go func() {
+		time.Sleep(300 * time.Millisecond)
+		io.WriteString(w, mathemtest.Email+"\n")
+		w.Close()
+	}()
+
+	out, err := runCLI(t, "--timeout", "200ms", "login", "-p", mathemtest.Password)
+	if err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	assertContains(t, out, "Successfully logged in as "+mathemtest.Email)
+}
+
+func TestLoginWrongPassword(t *testing.T) {
+	newTestServer(t)
+
+	_, err := runCLI(t, "login", "-e", mathemtest.Email, "-p", "wrong")
+	if err == nil {
+		t.Fatal("expected login to fail")
+	}
+	assertContains(t, err.Error(), "Felaktig e-postadress eller lösenord")
+}
+
+func TestNotLoggedIn(t *testing.T) {
+	newTestServer(t)
+
+	_, err := runCLI(t, "cart")
+	if err == nil || !strings.Contains(err.Error(), "not logged in") {
+		t.Fatalf("expected not logged in error, got %v", err)
+	}
+}
+
+func TestSearch(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "search", "mjölk")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out,
+		"Found 4 products (page 1)",
+		"[3681] ✓ Färsk Mellanmjölk 1,5%",
+		"[3690] ✗ Färsk Standardmjölk 3%",
+		"Price: 19,95 kr (13,30 kr/l)",
+	)
+}
+
+func TestSearchPaging(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	for i := range mathemtest.DefaultPageSize + 5 {
+		srv.AddProducts(mathemtest.Product(9000+i, "Testvara", "Test", "", "1.00", "", ""))
+	}
+
+	out, err := runCLI(t, "search", "testvara")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "More results available. Use --page 2")
+
+	out, err = runCLI(t, "search", "testvara", "--page", "2")
+	if err != nil {
+		t.Fatalf("search page 2: %v", err)
+	}
+	assertContains(t, out, "(page 2)", "[9020]")
+	if strings.Contains(out, "More results available") {
+		t.Errorf("last page should not offer more results:\n%s", out)
+	}
+}
+
+func TestSearchAllAndLimit(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	for i := range 25 {
+		srv.AddProducts(mathemtest.Product(9000+i, "Testvara", "Test", "", "1.00", "", ""))
+	}
+
+	out, err := runCLI(t, "search", "testvara", "--all", "--per-page", "10")
+	if err != nil {
+		t.Fatalf("search --all: %v", err)
+	}
+	assertContains(t, out, "[9000]", "[9024]", "Showed 25 products")
+	if got := srv.Requests("/search/mixed/"); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+
+	out, err = runCLI(t, "search", "testvara", "--limit", "3")
+	if err != nil {
+		t.Fatalf("search --limit: %v", err)
+	}
+	assertContains(t, out, "[9002]", "Showed 3 products")
+	if strings.Contains(out, "[9003]") {
+		t.Errorf("--limit 3 showed more than 3 products:\n%s", out)
+	}
+	if got := srv.Requests("/search/mixed/"); got != 4 {
+		t.Errorf("requests = %d, want 4", got)
+	}
+
+	if _, err := runCLI(t, "search", "testvara", "--all", "--page", "2"); err == nil {
+		t.Error("expected --all and --page to be rejected together")
+	}
+}
+
+func TestSearchFilters(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "search", "kaffe", "--max-unit-price", "140", "--sort", "unit-price")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "2 match the filters")
+	// Garant Eko at 119,90 kr/kg comes before Löfbergs at 139 kr/kg
+	if i, j := strings.Index(out, "[4430]"), strings.Index(out, "[4420]"); i < 0 || j < 0 || i > j {
+		t.Errorf("results not sorted by unit price:\n%s", out)
+	}
+	if strings.Contains(out, "[4410]") {
+		t.Errorf("unit price filter not applied:\n%s", out)
+	}
+
+	out, err = runCLI(t, "search", "kaffe", "--on-offer", "--exclude", "skånerost")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "[4410]")
+	for _, id := range []string{"[4411]", "[4420]", "[4430]"} {
+		if strings.Contains(out, id) {
+			t.Errorf("filtered product %s shown:\n%s", id, out)
+		}
+	}
+
+	out, err = runCLI(t, "search", "mjölk", "--available-only", "--brand", "arla ko®", "--max-price", "20")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "[3681]", "[3682]")
+	if strings.Contains(out, "[3690]") || strings.Contains(out, "[3683]") {
+		t.Errorf("filtered products shown:\n%s", out)
+	}
+
+	if _, err := runCLI(t, "search", "kaffe", "--sort", "weight"); err == nil {
+		t.Error("expected an invalid --sort to be rejected")
+	}
+}
+
+func TestSearchUnitPriceNormalized(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	srv.AddProducts(
+		// 12 kr/hg is 120 kr/kg, dearer than Garant Eko at 119,90 kr/kg
+		mathemtest.Product(9101, "Kaffe Lösvikt", "Test", "", "12.00", "12.00", "hg"),
+		mathemtest.Product(9102, "Kaffekapslar", "Test", "10 st", "39.00", "3.90", "st"),
+		mathemtest.Product(9103, "Kaffe Presentask", "Test", "", "89.00", "", ""),
+	)
+
+	out, err := runCLI(t, "search", "kaffe", "--sort", "unit-price")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	// Per kg first, cheapest first, then per st, then no unit price
+	last := -1
+	for _, id := range []string{"[4430]", "[9101]", "[4420]", "[4410]", "[9102]", "[9103]"} {
+		i := strings.Index(out, id)
+		if i < last {
+			t.Errorf("%s out of order:\n%s", id, out)
+		}
+		last = i
+	}
+
+	out, err = runCLI(t, "search", "kaffe", "--max-unit-price", "100")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "[9102]")
+	for _, id := range []string{"[9101]", "[4430]", "[9103]"} {
+		if strings.Contains(out, id) {
+			t.Errorf("%s passed --max-unit-price 100:\n%s", id, out)
+		}
+	}
+}
+
+func TestSearchFiltersAcrossPages(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	// The cheapest products are on the last page
+	for i := range 25 {
+		srv.AddProducts(mathemtest.Product(9000+i, "Testvara", "Test", "", fmt.Sprintf("%d.00", 100-i), "", ""))
+	}
+
+	out, err := runCLI(t, "search", "testvara", "--sort", "price", "--limit", "3", "--per-page", "10")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "Showed 3 products")
+	if i, j, k := strings.Index(out, "[9024]"), strings.Index(out, "[9023]"), strings.Index(out, "[9022]"); i < 0 || i > j || j > k {
+		t.Errorf("expected the three cheapest in order:\n%s", out)
+	}
+	if got := srv.Requests("/search/mixed/"); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+
+	out, err = runCLI(t, "search", "testvara", "--all", "--max-price", "78")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "[9022]", "[9024]", "Showed 3 products")
+
+	out, err = runCLI(t, "search", "testvara", "--all", "--on-offer")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "No products match the filters")
+}
+
+func TestCompare(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	srv.AddProducts(
+		// No unit price, so it is computed from the pack size: 100 kr/kg
+		mathemtest.Product(9100, "Kaffe Mellanrost Portionspåsar", "Test", "4 x 125 g", "50.00", "", ""),
+		// Per hg is converted to per kg: 120 kr/kg
+		mathemtest.Product(9101, "Kaffe Lösvikt", "Test", "", "12.00", "12.00", "hg"),
+		mathemtest.Product(9102, "Kaffekapslar", "Test", "10 st", "39.00", "", ""),
+		mathemtest.Product(9103, "Kaffemugg", "Test", "", "89.00", "", ""),
+	)
+
+	out, err := runCLI(t, "compare", "kaffe")
+	if err != nil {
+		t.Fatalf("compare: %v", err)
+	}
+	assertContains(t, out,
+		"Price per kg:",
+		"  1.   100,00 kr/kg*  [9100] Test Kaffe Mellanrost Portionspåsar, 4 x 125 g (50,00 kr)",
+		"  2.   119,90 kr/kg   [4430]",
+		"  3.   120,00 kr/kg   [9101] Test Kaffe Lösvikt (12,00 kr)",
+		"Price per st:",
+		"  1.     3,90 kr/st*  [9102]",
+		"No unit price or pack size:",
+		"  [9103] Test Kaffemugg (89,00 kr)",
+		"* computed from the pack size",
+	)
+	// kr/kg, with the most products, comes before kr/st
+	if strings.Index(out, "Price per kg:") > strings.Index(out, "Price per st:") {
+		t.Errorf("groups in the wrong order:\n%s", out)
+	}
+}
+
+func TestSearchFile(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	queries := filepath.Join(t.TempDir(), "queries.txt")
+	list := "# Weekly list\nmjölk\n\nkaffe\nfinnsinte\nbananer\n"
+	if err := os.WriteFile(queries, []byte(list), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, "search", "-f", queries, "--concurrency", "3", "--limit", "2", "--sort", "price")
+	if err != nil {
+		t.Fatalf("search -f: %v", err)
+	}
+	assertContains(t, out, "=== mjölk ===", "=== kaffe ===", "[4430]", "=== finnsinte ===\n\nNo products found", "[5501]")
+
+	// Results come in input order whichever search finishes first
+	last := -1
+	for _, heading := range []string{"=== mjölk ===", "=== kaffe ===", "=== finnsinte ===", "=== bananer ==="} {
+		i := strings.Index(out, heading)
+		if i < last {
+			t.Errorf("%s out of order:\n%s", heading, out)
+		}
+		last = i
+	}
+	if strings.Contains(out, "Weekly list") {
+		t.Errorf("comment searched:\n%s", out)
+	}
+	if got := srv.Requests("/search/mixed/"); got != 4 {
+		t.Errorf("requests = %d, want 4", got)
+	}
+
+	// Queries can come from stdin too
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.WriteString("gouda\n")
+	w.Close()
+	stdin := os.Stdin
+	os.Stdin = r
+	defer func() { os.Stdin = stdin }()
+
+	out, err = runCLI(t, "search", "-f", "-")
+	if err != nil {
+		t.Fatalf("search -f -: %v", err)
+	}
+	assertContains(t, out, "=== gouda ===", "[2352]")
+
+	if _, err := runCLI(t, "search", "-f", queries, "kaffe"); err == nil {
+		t.Error("expected a query argument with --file to be rejected")
+	}
+}
+
+func TestCartAddShowClear(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "cart")
+	if err != nil {
+		t.Fatalf("cart: %v", err)
+	}
+	assertContains(t, out, "Your cart is empty")
+
+	if _, err := runCLI(t, "cart", "add", "3681", "2"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+	out, err = runCLI(t, "cart", "add", "3681")
+	if err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+	assertContains(t, out, "Added 1 item(s) to cart", "Cart total: 59,85 kr (3 items)")
+
+	if got := srv.CartQuantity(3681); got != 3 {
+		t.Errorf("quantities should be additive: got %d, want 3", got)
+	}
+
+	out, err = runCLI(t, "cart", "show")
+	if err != nil {
+		t.Fatalf("cart show: %v", err)
+	}
+	assertContains(t, out, "[3681] Arla Ko® Färsk Mellanmjölk 1,5%", "Qty: 3 × 19,95 kr = 59,85 kr")
+
+	if _, err := runCLI(t, "cart", "clear"); err != nil {
+		t.Fatalf("cart clear: %v", err)
+	}
+	if got := srv.CartQuantity(3681); got != 0 {
+		t.Errorf("cart not cleared: %d left", got)
+	}
+}
+
+func TestCartAddUnknownProduct(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	_, err := runCLI(t, "cart", "add", "1")
+	if err == nil {
+		t.Fatal("expected error for unknown product")
+	}
+	assertContains(t, err.Error(), "product_id: Product 1 does not exist (does_not_exist)")
+}
+
+func TestSessionExpiredHint(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	srv.ExpireSessions()
+
+	_, err := runCLI(t, "cart")
+	if err == nil {
+		t.Fatal("expected error for expired session")
+	}
+	assertContains(t, errorHint(err), "mathemcli login")
+}
+
+func TestSearchRetriesServerErrors(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	srv.Fail("/search/mixed/", mathemtest.Failure{Status: 503, RetryAfter: "0"})
+
+	out, err := runCLI(t, "search", "kaffe")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "Bryggkaffe Mellanrost")
+
+	if got := srv.Requests("/search/mixed/"); got != 2 {
+		t.Errorf("requests = %d, want 2", got)
+	}
+}
+
+func TestCartAddIsNotRetried(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	srv.Fail("/cart/items/", mathemtest.Failure{Status: 503, RetryAfter: "0"})
+
+	if _, err := runCLI(t, "cart", "add", "3681"); err == nil {
+		t.Fatal("expected cart add to fail")
+	}
+	if got := srv.Requests("/cart/items/"); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := newTestServer(t)
+	dir := t.TempDir()
+
+	if _, err := runCLI(t, "--record", dir, "login", "-e", mathemtest.Email, "-p", mathemtest.Password); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+	session, _ := config.LoadSession()
+
+	recorded, err := runCLI(t, "--record", dir, "search", "kaffe")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+
+	cassette, err := os.ReadFile(filepath.Join(dir, transport.CassetteFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{mathemtest.Password, session.SessionID, session.CSRFToken} {
+		if bytes.Contains(cassette, []byte(secret)) {
+			t.Errorf("cassette contains secret %q", secret)
+		}
+	}
+
+	// Replay works without the server and without a saved login
+	srv.Close()
+	if _, err := runCLI(t, "logout"); err != nil {
+		t.Fatal(err)
+	}
+
+	replayed, err := runCLI(t, "--replay", dir, "search", "kaffe")
+	if err != nil {
+		t.Fatalf("replayed search: %v", err)
+	}
+	if replayed != recorded {
+		t.Errorf("replayed output differs:\n%s\nwant:\n%s", replayed, recorded)
+	}
+
+	if _, err := runCLI(t, "--replay", dir, "search", "te"); err == nil {
+		t.Error("expected error for a request that was not recorded")
+	}
+}
+
+func TestHARExport(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	session, _ := config.LoadSession()
+	harPath := filepath.Join(t.TempDir(), "trace.har")
+
+	if _, err := runCLI(t, "--har", harPath, "search", "kaffe"); err != nil {
+		t.Fatalf("search: %v", err)
+	}
+
+	data, err := os.ReadFile(harPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte(session.SessionID)) {
+		t.Error("HAR file contains the session ID")
+	}
+
+	var har transport.HAR
+	if err := json.Unmarshal(data, &har); err != nil {
+		t.Fatalf("invalid HAR: %v", err)
+	}
+	if har.Log.Version != "1.2" || len(har.Log.Entries) != 1 {
+		t.Fatalf("unexpected HAR log: version %q, %d entries", har.Log.Version, len(har.Log.Entries))
+	}
+	if entry := har.Log.Entries[0]; entry.Request.Method != "GET" || entry.Response.Status != 200 {
+		t.Errorf("unexpected entry: %s -> %d", entry.Request.Method, entry.Response.Status)
+	}
+}
+
+func TestVerboseTrace(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	session, _ := config.LoadSession()
+	session.CSRFToken = "test-csrf-token"
+	if err := config.SaveSession(session); err != nil {
+		t.Fatal(err)
+	}
+
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stderr := os.Stderr
+	os.Stderr = w
+	defer func() { os.Stderr = stderr }()
+
+	var trace bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		io.Copy(&trace, r)
+		close(done)
+	}()
+
+	_, searchErr := runCLI(t, "-v", "search", "kaffe")
+	_, addErr := runCLI(t, "--verbose", "cart", "add", "3681")
+	os.Stderr = stderr
+	w.Close()
+	<-done
+
+	if searchErr != nil {
+		t.Fatalf("search: %v", searchErr)
+	}
+	if addErr != nil {
+		t.Fatalf("cart add: %v", addErr)
+	}
+
+	out := trace.String()
+	assertContains(t, out,
+		"> GET "+srv.APIURL()+"/search/mixed/?",
+		"< 200 OK (",
+		"> POST "+srv.APIURL()+"/cart/items/",
+		"> User-Agent: Mozilla/5.0",
+		"> Sec-Ch-Ua: ",
+		"sessionid="+transport.Redacted,
+		"csrftoken="+transport.Redacted,
+	)
+	for _, secret := range []string{session.SessionID, session.CSRFToken} {
+		if strings.Contains(out, secret) {
+			t.Errorf("trace contains a session secret %q:\n%s", secret, out)
+		}
+	}
+}
+
+func TestSlots(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	days := mathemtest.SampleSlots(time.Now(), 3)
+
+	out, err := runCLI(t, "slots")
+	if err != nil {
+		t.Fatalf("slots: %v", err)
+	}
+	first := days[0].Slots
+	assertContains(t, out,
+		"["+first[0].ID+"] ✓ 08:00–10:00     49,00 kr",
+		"["+first[1].ID+"] ✓ 10:00–12:00     39,00 kr  eco",
+		"["+first[3].ID+"] ✗ 17:00–19:00",
+		days[2].Date,
+	)
+
+	out, err = runCLI(t, "slots", "--after", "17:00", "--max-fee", "49")
+	if err != nil {
+		t.Fatalf("slots with filters: %v", err)
+	}
+	assertContains(t, out, "19:00–21:00")
+	for _, hidden := range []string{"08:00", "13:00", "17:00–19:00"} {
+		if strings.Contains(out, hidden) {
+			t.Errorf("filtered output contains %s:\n%s", hidden, out)
+		}
+	}
+
+	out, err = runCLI(t, "slots", "--date", days[2].Date)
+	if err != nil {
+		t.Fatalf("slots --date: %v", err)
+	}
+	assertContains(t, out, days[2].Date)
+	if strings.Contains(out, days[0].Date) {
+		t.Errorf("output contains other days:\n%s", out)
+	}
+
+	if _, err := runCLI(t, "slots", "--after", "5pm"); err == nil {
+		t.Error("expected error for invalid --after")
+	}
+}
+
+func TestSlotReservation(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	slot := mathemtest.SampleSlots(time.Now(), 1)[0].Slots[1]
+
+	out, err := runCLI(t, "slots", "current")
+	if err != nil {
+		t.Fatalf("slots current: %v", err)
+	}
+	assertContains(t, out, "No delivery slot reserved (or the unverified reservation endpoint does not exist")
+
+	out, err = runCLI(t, "slots", "reserve", slot.ID)
+	if err != nil {
+		t.Fatalf("slots reserve: %v", err)
+	}
+	assertContains(t, out, "Slot reserved", "["+slot.ID+"]", "10:00–12:00, 39,00 kr, eco", "Held for 1 h")
+	if got := srv.ReservedSlot(); got != slot.ID {
+		t.Errorf("reserved slot = %q, want %q", got, slot.ID)
+	}
+
+	out, err = runCLI(t, "slots", "current")
+	if err != nil {
+		t.Fatalf("slots current: %v", err)
+	}
+	assertContains(t, out, "["+slot.ID+"]", "Held for")
+
+	out, err = runCLI(t, "slots", "release")
+	if err != nil {
+		t.Fatalf("slots release: %v", err)
+	}
+	assertContains(t, out, "Slot released")
+	if got := srv.ReservedSlot(); got != "" {
+		t.Errorf("slot still reserved: %q", got)
+	}
+
+	out, err = runCLI(t, "slots", "release")
+	if err != nil {
+		t.Fatalf("slots release: %v", err)
+	}
+	assertContains(t, out, "No delivery slot reserved (or the unverified reservation endpoint does not exist")
+}
+
+func TestSlotReserveFull(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	full := mathemtest.SampleSlots(time.Now(), 1)[0].Slots[3]
+
+	_, err := runCLI(t, "slots", "reserve", full.ID)
+	if err == nil {
+		t.Fatal("expected error for a fully booked slot")
+	}
+	assertContains(t, err.Error(), "slot_id: Tiden är fullbokad (slot_full)")
+}
+
+func TestCartValidate(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	slot := mathemtest.SampleSlots(time.Now(), 1)[0].Slots[0]
+
+	// Empty cart, no slot
+	out, err := runCLI(t, "cart", "validate")
+	if got := exitCode(err); got != exitOtherProblem {
+		t.Fatalf("exit code = %d (%v), want %d", got, err, exitOtherProblem)
+	}
+	assertContains(t, out, "Other problems:", "(empty_cart)", "(no_delivery_slot)")
+
+	if _, err := runCLI(t, "slots", "reserve", slot.ID); err != nil {
+		t.Fatalf("slots reserve: %v", err)
+	}
+	if _, err := runCLI(t, "cart", "add", "3681", "2"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+	out, err = runCLI(t, "cart", "validate")
+	if got := exitCode(err); got != exitMinimumOrder {
+		t.Fatalf("exit code = %d (%v), want %d", got, err, exitMinimumOrder)
+	}
+	assertContains(t, out, "39,90 kr of 300,00 kr, add 260,10 kr more")
+
+	srv.SetQuantityLimit(4420, 2)
+	if _, err := runCLI(t, "cart", "add", "4420", "3"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+	out, err = runCLI(t, "cart", "validate")
+	if got := exitCode(err); got != exitQuantityLimit {
+		t.Fatalf("exit code = %d (%v), want %d", got, err, exitQuantityLimit)
+	}
+	assertContains(t, out, "[4420] Löfbergs Kaffe Mörkrost Hela Bönor: 3 in cart, max 2")
+
+	// Unavailable products take precedence over everything else
+	if _, err := runCLI(t, "cart", "add", "3690"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+	out, err = runCLI(t, "cart", "validate", "--json")
+	if got := exitCode(err); got != exitUnavailable {
+		t.Fatalf("exit code = %d (%v), want %d", got, err, exitUnavailable)
+	}
+	var v api.CartValidation
+	if err := json.Unmarshal([]byte(out), &v); err != nil {
+		t.Fatalf("invalid JSON output: %v\n%s", err, out)
+	}
+	if len(v.UnavailableProducts) != 1 || v.UnavailableProducts[0].ProductID != 3690 {
+		t.Errorf("unexpected unavailable products: %+v", v.UnavailableProducts)
+	}
+
+	if _, err := runCLI(t, "cart", "clear"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(t, "cart", "add", "4420", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(t, "cart", "add", "3681", "2"); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCLI(t, "cart", "validate")
+	if err != nil {
+		t.Fatalf("cart validate: %v\n%s", err, out)
+	}
+	assertContains(t, out, "Cart is ready to order")
+
+	// A response in another shape is a failed check, not a blocked cart
+	srv.Fail("/cart/validate/", mathemtest.Failure{Status: 200, Body: `{"valid": true}`})
+	_, err = runCLI(t, "cart", "validate")
+	if got := exitCode(err); got != 1 {
+		t.Fatalf("exit code = %d (%v), want 1", got, err)
+	}
+	if !strings.Contains(err.Error(), "unexpected cart validation response") {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestDeals(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "deals")
+	if err != nil {
+		t.Fatalf("deals: %v", err)
+	}
+	assertContains(t, out,
+		"Found 3 deals",
+		"[2352] ✓ Gouda 28%",
+		"Offer: -20% (was 139,00 kr), until ",
+		"Offer: 2 för 110 kr (save 15%), until ",
+	)
+
+	out, err = runCLI(t, "deals", "--min-discount", "20")
+	if err != nil {
+		t.Fatalf("deals --min-discount: %v", err)
+	}
+	assertContains(t, out, "Found 1 deals", "[2352]")
+
+	out, err = runCLI(t, "deals", "--brand", "zoégas", "skåne")
+	if err != nil {
+		t.Fatalf("deals --brand: %v", err)
+	}
+	assertContains(t, out, "Found 1 deals", "[4411]")
+
+	// Offers show up in search results too
+	out, err = runCLI(t, "search", "gouda")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "Offer: -20% (was 139,00 kr)")
+}
+
+func TestPerks(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "perks")
+	if err != nil {
+		t.Fatalf("perks: %v", err)
+	}
+	assertContains(t, out,
+		"Active perks:",
+		"[101] Fri leverans",
+		"Conditions: Handla för minst 800 kr",
+		"Expires: ",
+		"Available perks",
+		"[102] 20 kr rabatt på Zoégas",
+	)
+	if strings.Index(out, "[102]") < strings.Index(out, "Available perks") {
+		t.Errorf("inactive perk listed as active:\n%s", out)
+	}
+}
+
+func TestCartShowsPerks(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	// 129,90 kr of Zoégas plus 4 × 139 kr is 685,90 kr, close to 800 kr
+	if _, err := runCLI(t, "cart", "add", "4410", "2"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := runCLI(t, "cart", "add", "4420", "4"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, "cart")
+	if err != nil {
+		t.Fatalf("cart: %v", err)
+	}
+	assertContains(t, out,
+		"Perks:",
+		"✓ [102] 20 kr rabatt på Zoégas qualifies but is not active",
+		"… [101] Fri leverans: add 114,10 kr more",
+	)
+
+	if _, err := runCLI(t, "cart", "add", "4420"); err != nil {
+		t.Fatal(err)
+	}
+	out, err = runCLI(t, "cart")
+	if err != nil {
+		t.Fatalf("cart: %v", err)
+	}
+	assertContains(t, out, "✓ [101] Fri leverans applies")
+	if strings.Contains(out, "[103]") {
+		t.Errorf("perk 103 needs bananas and should not be shown:\n%s", out)
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "suggest", "mell")
+	if err != nil {
+		t.Fatalf("suggest: %v", err)
+	}
+	if want := "mellanmjölk\nmellanrost\n"; out != want {
+		t.Errorf("suggest output = %q, want %q", out, want)
+	}
+
+	srv.Fail("/search/mixed/", mathemtest.Failure{Status: 200, Body: `{"items": [{"attributes": {"title": "mellanmjölk"}}, {"attributes": {"text": "mellanrost"}}]}`})
+	out, err = runCLI(t, "suggest", "mell")
+	if err != nil {
+		t.Fatalf("suggest: %v", err)
+	}
+	if want := "mellanrost\n"; out != want {
+		t.Errorf("suggest output = %q, want %q", out, want)
+	}
+}
+
+func TestSearchDidYouMean(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "search", "mellanmjolk")
+	if err != nil {
+		t.Fatalf("search: %v", err)
+	}
+	assertContains(t, out, "No products found", "Did you mean: mellanmjölk?")
+}
+
+func TestProduct(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "product", "3683")
+	if err != nil {
+		t.Fatalf("product: %v", err)
+	}
+	assertContains(t, out,
+		"[3683] Garant Eko Mellanmjölk Ekologisk 1,5%",
+		"Compare price: 16,95 kr/l",
+		"Origin:        Sverige",
+		"Labels:        Ekologisk, KRAV",
+		"Allergens:     Mjölk",
+		"EAN:           7340011488472",
+		"Storage:       Förvaras kylt",
+		"URL:           "+srv.URL+"/se/products/3683/",
+		"Ingredients:\n  Pastöriserad ekologisk mellanmjölk",
+		"Nutrition (per 100 ml):",
+		"Protein        3,5 g",
+	)
+
+	// Products without details still show what search knows
+	out, err = runCLI(t, "product", "2352")
+	if err != nil {
+		t.Fatalf("product: %v", err)
+	}
+	assertContains(t, out, "[2352] Arla Gouda 28%", "Offer:         -20%")
+	if strings.Contains(out, "Nutrition") {
+		t.Errorf("empty nutrition table shown:\n%s", out)
+	}
+
+	_, err = runCLI(t, "product", "1")
+	if err == nil {
+		t.Fatal("expected error for unknown product")
+	}
+	assertContains(t, errorHint(err), "does not exist")
+	assertContains(t, err.Error(), "unverified product endpoint")
+}
+
+func TestCartSetRemoveDec(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	if _, err := runCLI(t, "cart", "add", "3681", "3"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+
+	out, err := runCLI(t, "cart", "set", "3681", "5")
+	if err != nil {
+		t.Fatalf("cart set: %v", err)
+	}
+	assertContains(t, out, "[3681] Arla Ko® Färsk Mellanmjölk 1,5%: 3 → 5", "Cart total: 99,75 kr (5 items)")
+
+	out, err = runCLI(t, "cart", "set", "5502", "1")
+	if err != nil {
+		t.Fatalf("cart set new product: %v", err)
+	}
+	assertContains(t, out, "[5502] Kronägg Ägg 12-pack Frigående: 0 → 1")
+
+	out, err = runCLI(t, "cart", "dec", "3681")
+	if err != nil {
+		t.Fatalf("cart dec: %v", err)
+	}
+	assertContains(t, out, "5 → 4")
+
+	out, err = runCLI(t, "cart", "dec", "3681", "10")
+	if err != nil {
+		t.Fatalf("cart dec: %v", err)
+	}
+	assertContains(t, out, "4 → 0")
+	if got := srv.CartQuantity(3681); got != 0 {
+		t.Errorf("quantity after dec = %d, want 0", got)
+	}
+
+	out, err = runCLI(t, "cart", "remove", "5502", "4410")
+	if err != nil {
+		t.Fatalf("cart remove: %v", err)
+	}
+	assertContains(t, out, "1 → 0", "[4410] is not in the cart", "Cart total: 0,00 kr (0 items)")
+
+	if _, err := runCLI(t, "cart", "set", "3681", "-1"); err == nil {
+		t.Error("expected error for negative quantity")
+	}
+}
+
+func TestCartRemoveUnsupported(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	if _, err := runCLI(t, "cart", "add", "3681", "2"); err != nil {
+		t.Fatalf("cart add: %v", err)
+	}
+
+	// If the cart endpoint ignores negative quantities, say so rather than
+	// reporting a removal that didn't happen
+	srv.IgnoreNegativeQuantities()
+	_, err := runCLI(t, "cart", "remove", "3681")
+	if err == nil {
+		t.Fatal("expected an error when the cart keeps the product")
+	}
+	assertContains(t, err.Error(), "[3681] is 2, not 0")
+}
+
+func TestCartGroupByCategories(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	for _, id := range []string{"3681", "4410", "3682"} {
+		if _, err := runCLI(t, "cart", "add", id); err != nil {
+			t.Fatalf("cart add %s: %v", id, err)
+		}
+	}
+
+	out, err := runCLI(t, "cart", "--group-by", "categories")
+	if err != nil {
+		t.Fatalf("cart: %v", err)
+	}
+	assertContains(t, out,
+		"── Mejeri & ägg ──",
+		"Mejeri & ägg total            34,45 kr",
+		"── Kaffe & te ──",
+		"Kaffe & te total              64,95 kr",
+	)
+	dairy, milk, coffee := strings.Index(out, "Mejeri & ägg ──"), strings.Index(out, "[3682]"), strings.Index(out, "── Kaffe & te")
+	if !(dairy < milk && milk < coffee) {
+		t.Errorf("items are not under their department:\n%s", out)
+	}
+
+	out, err = runCLI(t, "cart", "show", "-g", "none")
+	if err != nil {
+		t.Fatalf("cart show: %v", err)
+	}
+	if strings.Contains(out, "──  ") || strings.Contains(out, " total ") {
+		t.Errorf("ungrouped cart has group headings:\n%s", out)
+	}
+
+	if _, err := runCLI(t, "cart", "--group-by", "brand"); err == nil {
+		t.Error("expected error for invalid --group-by")
+	}
+	if _, err := runCLI(t, "cart", "add", "3681", "--group-by", "categories"); err == nil {
+		t.Error("cart add accepted --group-by")
+	}
+	if got := srv.CartQuantity(3681); got != 1 {
+		t.Errorf("cart add with --group-by changed the cart: quantity %d", got)
+	}
+}
+
+func TestOrders(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	out, err := runCLI(t, "orders")
+	if err != nil {
+		t.Fatalf("orders: %v", err)
+	}
+	assertContains(t, out,
+		"Upcoming orders:\n  [5002]",
+		"111,20 kr  confirmed",
+		"Past orders:\n  [5001]",
+		"17:00–19:00",
+		"152,72 kr  delivered",
+	)
+
+	out, err = runCLI(t, "orders", "show", "5001")
+	if err != nil {
+		t.Fatalf("orders show: %v", err)
+	}
+	assertContains(t, out,
+		"Order M105001 (delivered)",
+		"[3681] ✓ Arla Ko® Färsk Mellanmjölk 1,5%",
+		"Qty: 2 × 19,95 kr = 39,90 kr",
+		"[3690] ✗ Arla Ko® Färsk Standardmjölk 3%",
+	)
+
+	if _, err := runCLI(t, "orders", "show", "1"); err == nil {
+		t.Error("expected error for unknown order")
+	}
+}
+
+func TestOrdersReorder(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	if _, err := runCLI(t, "cart", "add", "3681"); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := runCLI(t, "orders", "reorder", "5001")
+	if err != nil {
+		t.Fatalf("orders reorder: %v", err)
+	}
+	assertContains(t, out,
+		"Added 9 item(s) from order M105001 to cart",
+		"Not available:\n  [3690] Arla Ko® Färsk Standardmjölk 3% (Tillfälligt slut)",
+	)
+
+	want := map[int]int{3681: 3, 4410: 1, 3690: 0, 5501: 6}
+	for id, qty := range want {
+		if got := srv.CartQuantity(id); got != qty {
+			t.Errorf("cart quantity of %d = %d, want %d", id, got, qty)
+		}
+	}
+	if got := srv.Requests("/cart/items/"); got != 2 {
+		t.Errorf("cart requests = %d, want 2 (one for the add, one batched reorder)", got)
+	}
+}
+
+func TestOrdersReorderUnknownAvailability(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+
+	// A line without availability data isn't assumed to be available
+	mystery := mathemtest.Product(9200, "Okänd vara", "Test", "", "10.00", "", "")
+	mystery.Attributes.Availability = api.Availability{}
+	srv.AddProducts(mystery)
+	id := srv.AddOrder("delivered", time.Now().AddDate(0, 0, -3),
+		api.CartItem{ProductID: 3681, Quantity: 1},
+		api.CartItem{ProductID: 9200, Quantity: 2},
+	)
+
+	out, err := runCLI(t, "orders", "reorder", strconv.Itoa(id))
+	if err != nil {
+		t.Fatalf("orders reorder: %v", err)
+	}
+	assertContains(t, out, "Added 1 item(s)", "Availability unknown, not added:\n  [9200] Test Okänd vara")
+	if got := srv.CartQuantity(9200); got != 0 {
+		t.Errorf("cart quantity of 9200 = %d, want 0", got)
+	}
+}
+
+func TestWhoami(t *testing.T) {
+	newTestServer(t)
+
+	if _, err := runCLI(t, "login", "-e", mathemtest.Email, "-p", mathemtest.Password); err != nil {
+		t.Fatalf("login: %v", err)
+	}
+
+	out, err := runCLI(t, "whoami")
+	if err != nil {
+		t.Fatalf("whoami: %v", err)
+	}
+	assertContains(t, out,
+		"Account:     "+mathemtest.Email,
+		"Logged in:   "+time.Now().Format(time.DateOnly),
+		"Expires:     ~"+time.Now().Add(config.SessionLifetime).Format(time.DateOnly)+" (in about 30 days)",
+		"Session:     valid",
+	)
+}
+
+func TestStatusExpiredSession(t *testing.T) {
+	srv := newTestServer(t)
+	loginTestSession(t, srv)
+	srv.ExpireSessions()
+
+	out, err := runCLI(t, "status")
+	if err == nil {
+		t.Fatal("expected error for expired session")
+	}
+	assertContains(t, out, "Account:     "+mathemtest.Email, "Logged in:   unknown", "Session:     rejected by Mathem")
+	assertContains(t, errorHint(err), "mathemcli login")
+}
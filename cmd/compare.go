@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var (
+	compareLimit         int
+	compareAvailableOnly bool
+)
+
+var compareCmd = &cobra.Command{
+	Use:   "compare [query]",
+	Short: "Rank search results by unit price",
+	Long: `Search for products and rank them by price per kilo, litre or piece,
+cheapest first. Unit prices in other units, like kr/hg or kr/dl, are
+converted, and products in units that can't be compared are listed in
+separate groups.
+
+Products without a unit price get one computed from their pack size, e.g.
+"4 x 125 g" or "1,5 l"; those are marked with *.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		query := strings.Join(args, " ")
+
+		if compareLimit < 1 {
+			return fmt.Errorf("invalid --limit %d", compareLimit)
+		}
+
+		groups := make(map[string][]unitPriced)
+		var unknown []api.Product
+		count := 0
+		for item, err := range client.SearchAll(cmd.Context(), query) {
+			if err != nil {
+				return fmt.Errorf("search failed: %w", err)
+			}
+			if compareAvailableOnly && !item.Attributes.Availability.IsAvailable {
+				continue
+			}
+
+			if up, ok := unitPrice(item); ok {
+				groups[up.unit] = append(groups[up.unit], up)
+			} else {
+				unknown = append(unknown, item)
+			}
+
+			count++
+			if count == compareLimit {
+				break
+			}
+		}
+
+		if count == 0 {
+			fmt.Println("No products found")
+			printDidYouMean(cmd.Context(), query)
+			return nil
+		}
+
+		// The unit most products share comes first
+		units := make([]string, 0, len(groups))
+		for unit := range groups {
+			units = append(units, unit)
+		}
+		slices.SortFunc(units, func(a, b string) int {
+			return cmp.Or(cmp.Compare(len(groups[b]), len(groups[a])), cmp.Compare(a, b))
+		})
+
+		estimated := false
+		for i, unit := range units {
+			if i > 0 {
+				fmt.Println()
+			}
+			fmt.Printf("Price per %s:\n", unit)
+
+			ranked := groups[unit]
+			slices.SortStableFunc(ranked, func(a, b unitPriced) int {
+				return a.price.Cmp(b.price)
+			})
+			for rank, up := range ranked {
+				mark := " "
+				if up.estimated {
+					mark = "*"
+					estimated = true
+				}
+				attr := up.product.Attributes
+				fmt.Printf("%3d. %14s%s  [%d] %s", rank+1, up.price.String()+"/"+unit, mark, up.product.ID, attr.FullName)
+				if attr.NameExtra != "" {
+					fmt.Printf(", %s", attr.NameExtra)
+				}
+				fmt.Printf(" (%s)\n", attr.GrossPrice)
+			}
+		}
+
+		if len(unknown) > 0 {
+			if len(units) > 0 {
+				fmt.Println()
+			}
+			fmt.Println("No unit price or pack size:")
+			for _, p := range unknown {
+				fmt.Printf("  [%d] %s", p.ID, p.Attributes.FullName)
+				if p.Attributes.NameExtra != "" {
+					fmt.Printf(", %s", p.Attributes.NameExtra)
+				}
+				fmt.Printf(" (%s)\n", p.Attributes.GrossPrice)
+			}
+		}
+
+		if estimated {
+			fmt.Println()
+			fmt.Println("* computed from the pack size")
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	compareCmd.Flags().IntVarP(&compareLimit, "limit", "l", 100, "Compare up to this many products")
+	compareCmd.Flags().BoolVar(&compareAvailableOnly, "available-only", false, "Only compare products in stock")
+}
@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var (
+	dealsBrand       string
+	dealsMinDiscount int
+)
+
+var dealsCmd = &cobra.Command{
+	Use:   "deals [text]",
+	Short: "List products on offer",
+	Long: `List promoted products and campaigns, optionally filtered by name,
+brand or discount.`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		text := strings.ToLower(strings.Join(args, " "))
+
+		products, err := client.GetPromotedProducts(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get deals: %w", err)
+		}
+
+		var deals []api.Product
+		for _, p := range products {
+			attr := p.Attributes
+			if text != "" && !strings.Contains(strings.ToLower(attr.FullName+" "+attr.Name+" "+attr.Brand), text) {
+				continue
+			}
+			if dealsBrand != "" && !strings.EqualFold(attr.Brand, dealsBrand) {
+				continue
+			}
+			if dealsMinDiscount > 0 && attr.DiscountPercent() < dealsMinDiscount {
+				continue
+			}
+			deals = append(deals, p)
+		}
+
+		if len(deals) == 0 {
+			fmt.Println("No deals found")
+			return nil
+		}
+
+		fmt.Printf("Found %d deals:\n\n", len(deals))
+		for _, p := range deals {
+			printProduct(p)
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	dealsCmd.Flags().StringVarP(&dealsBrand, "brand", "b", "", "Only show deals from this brand")
+	dealsCmd.Flags().IntVar(&dealsMinDiscount, "min-discount", 0, "Only show deals saving at least this many percent")
+}
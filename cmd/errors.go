@@ -0,0 +1,54 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+// errorHint suggests what the user can do about an error, if anything
+func errorHint(err error) string {
+	switch {
+	case errors.Is(err, api.ErrSessionExpired):
+		return "Your session has expired or was rejected. Run 'mathemcli login' to sign in again."
+	case errors.Is(err, api.ErrRateLimited):
+		return "Mathem is rate limiting requests. Wait a minute and try again."
+	case errors.Is(err, api.ErrNotFound):
+		return "The requested item does not exist. Check the ID and try again."
+	case errors.Is(err, api.ErrServer):
+		return "Mathem is having problems right now. Try again later."
+	case errors.Is(err, context.DeadlineExceeded):
+		return "The command timed out. Try a longer --timeout."
+	}
+	return ""
+}
+
+// exitError is returned by commands that report a result through a
+// specific exit status, such as 'cart validate'
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// exitCode returns the process exit status for an error returned by a
+// command: 0 for nil, the code of an exitError, and 1 for anything else
+func exitCode(err error) int {
+	if err == nil {
+		return 0
+	}
+
+	var ee *exitError
+	if errors.As(err, &ee) {
+		return ee.code
+	}
+	return 1
+}
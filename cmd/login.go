@@ -6,6 +6,7 @@ import (
 	"os"
 	"strings"
 	"syscall"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/thepsadmin/mathemcli/internal/api"
@@ -48,17 +49,30 @@ var loginCmd = &cobra.Command{
 			password = string(bytePassword)
 		}
 
+		startTimeout(cmd)
+
 		// Create client and attempt login
-		c := api.NewClient()
-		if err := c.Login(email, password); err != nil {
+		opts, err := clientOptions()
+		if err != nil {
+			return err
+		}
+		c := api.NewClient(opts...)
+		if err := c.Login(cmd.Context(), email, password); err != nil {
 			return fmt.Errorf("login failed: %w", err)
 		}
 
+		// A replayed session is scrubbed, don't overwrite the real one with it
+		if replayDir != "" {
+			fmt.Printf("Successfully logged in as %s (replayed, session not saved)\n", email)
+			return nil
+		}
+
 		// Save session
 		session := &config.Session{
 			SessionID: c.SessionID(),
 			CSRFToken: c.CSRFToken(),
 			Email:     email,
+			CreatedAt: time.Now(),
 		}
 		if err := config.SaveSession(session); err != nil {
 			return fmt.Errorf("failed to save session: %w", err)
@@ -0,0 +1,194 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var ordersCmd = &cobra.Command{
+	Use:   "orders",
+	Short: "List past and upcoming orders",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		orders, err := client.GetOrders(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get orders: %w", err)
+		}
+
+		if len(orders) == 0 {
+			fmt.Println("You have no orders")
+			return nil
+		}
+
+		var upcoming, past []api.Order
+		for _, o := range orders {
+			if o.IsUpcoming {
+				upcoming = append(upcoming, o)
+			} else {
+				past = append(past, o)
+			}
+		}
+
+		if len(upcoming) > 0 {
+			fmt.Println("Upcoming orders:")
+			for _, o := range upcoming {
+				printOrderRow(o)
+			}
+		}
+
+		if len(past) > 0 {
+			if len(upcoming) > 0 {
+				fmt.Println()
+			}
+			fmt.Println("Past orders:")
+			for _, o := range past {
+				printOrderRow(o)
+			}
+		}
+
+		return nil
+	},
+}
+
+var ordersShowCmd = &cobra.Command{
+	Use:   "show [order_id]",
+	Short: "Show the products in an order",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid order ID: %w", err)
+		}
+
+		order, err := client.GetOrder(cmd.Context(), id)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		fmt.Printf("Order %s (%s)\n", order.OrderNumber, order.Status)
+		fmt.Printf("Delivery: %s\n\n", deliveryWindow(order.DeliveryStart, order.DeliveryEnd))
+
+		for _, line := range order.Lines {
+			availability := "✓"
+			if !line.Availability.IsAvailable {
+				availability = "✗"
+			}
+
+			fmt.Printf("[%d] %s %s\n", line.Product.ID, availability, line.Product.FullName)
+			if line.Product.NameExtra != "" {
+				fmt.Printf("     %s\n", line.Product.NameExtra)
+			}
+			fmt.Printf("     Qty: %d × %s = %s\n",
+				line.Quantity,
+				line.Product.GrossPrice,
+				line.DisplayPrice)
+			fmt.Println()
+		}
+
+		fmt.Println("─────────────────────────────────")
+		fmt.Printf("%-25s %12s\n", "Totalt", order.TotalGrossAmount)
+
+		return nil
+	},
+}
+
+var ordersReorderCmd = &cobra.Command{
+	Use:   "reorder [order_id]",
+	Short: "Add the products of an order to the cart",
+	Long: `Add every product from an order that is still available to the cart,
+in one request, and list the ones that are not. Lines the order doesn't
+give an availability for are listed too, and left out.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid order ID: %w", err)
+		}
+
+		order, err := client.GetOrder(cmd.Context(), id)
+		if err != nil {
+			return fmt.Errorf("failed to get order: %w", err)
+		}
+
+		var items []api.CartItem
+		var unavailable, unknown []api.OrderLine
+		added := 0
+		for _, line := range order.Lines {
+			// The order endpoints are unverified, so a line without an
+			// availability is not taken as available
+			if line.Availability == (api.Availability{}) {
+				unknown = append(unknown, line)
+				continue
+			}
+			if !line.Availability.IsAvailable {
+				unavailable = append(unavailable, line)
+				continue
+			}
+			items = append(items, api.CartItem{ProductID: line.Product.ID, Quantity: line.Quantity})
+			added += line.Quantity
+		}
+
+		if len(items) > 0 {
+			cart, err := client.AddToCart(cmd.Context(), items)
+			if err != nil {
+				return fmt.Errorf("failed to add to cart: %w", err)
+			}
+
+			fmt.Printf("Added %d item(s) from order %s to cart\n", added, order.OrderNumber)
+			fmt.Printf("Cart total: %s (%d items)\n",
+				cart.DisplayPrice, cart.ProductQuantityCount)
+		} else {
+			fmt.Println("Nothing from the order is available")
+		}
+
+		if len(unavailable) > 0 {
+			fmt.Println()
+			fmt.Println("Not available:")
+			for _, line := range unavailable {
+				fmt.Printf("  [%d] %s", line.Product.ID, line.Product.FullName)
+				if line.Availability.Description != "" {
+					fmt.Printf(" (%s)", line.Availability.Description)
+				}
+				fmt.Println()
+			}
+		}
+
+		if len(unknown) > 0 {
+			fmt.Println()
+			fmt.Println("Availability unknown, not added:")
+			for _, line := range unknown {
+				fmt.Printf("  [%d] %s\n", line.Product.ID, line.Product.FullName)
+			}
+		}
+
+		return nil
+	},
+}
+
+// printOrderRow prints an order as a line of the order list
+func printOrderRow(o api.Order) {
+	fmt.Printf("  [%d] %-34s %12s  %s\n",
+		o.ID, deliveryWindow(o.DeliveryStart, o.DeliveryEnd), o.TotalGrossAmount, o.Status)
+}
+
+// deliveryWindow formats a delivery time window, e.g.
+// "Saturday 2026-10-18 10:00–12:00"
+func deliveryWindow(start, end time.Time) string {
+	if start.IsZero() {
+		return "-"
+	}
+	window := start.Format("Monday 2006-01-02 15:04")
+	if !end.IsZero() {
+		window += "–" + end.Format("15:04")
+	}
+	return window
+}
+
+func init() {
+	ordersCmd.AddCommand(ordersShowCmd)
+	ordersCmd.AddCommand(ordersReorderCmd)
+}
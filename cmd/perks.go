@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+// almostQualifies is how close, as a fraction of a perk's minimum amount,
+// the cart has to be for the perk to be pointed out
+const almostQualifies = 0.2
+
+var perksCmd = &cobra.Command{
+	Use:   "perks",
+	Short: "List member perks and rewards",
+	Long: `List your active perks and the ones available to you, with their
+conditions and expiry dates.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		perks, err := client.GetPerks(cmd.Context())
+		if err != nil {
+			return fmt.Errorf("failed to get perks: %w", err)
+		}
+
+		if len(perks) == 0 {
+			fmt.Println("You have no perks")
+			return nil
+		}
+
+		var active, available []api.Perk
+		for _, p := range perks {
+			if p.IsActive {
+				active = append(active, p)
+			} else {
+				available = append(available, p)
+			}
+		}
+
+		if len(active) > 0 {
+			fmt.Printf("Active perks:\n\n")
+			for _, p := range active {
+				printPerk(p)
+			}
+		}
+
+		if len(available) > 0 {
+			fmt.Printf("Available perks:\n\n")
+			for _, p := range available {
+				printPerk(p)
+			}
+		}
+
+		return nil
+	},
+}
+
+// printPerk prints a perk with its conditions and expiry date
+func printPerk(p api.Perk) {
+	fmt.Printf("[%d] %s\n", p.ID, p.Title)
+	if p.Description != "" {
+		fmt.Printf("     %s\n", p.Description)
+	}
+	if p.Conditions != "" {
+		fmt.Printf("     Conditions: %s\n", p.Conditions)
+	}
+	if !p.ExpiresAt.IsZero() {
+		fmt.Printf("     Expires: %s\n", p.ExpiresAt.Format(time.DateOnly))
+	}
+	fmt.Println()
+}
+
+// printCartPerks points out perks the cart qualifies for or almost
+// qualifies for. Perks are a bonus, so failing to fetch them is not an
+// error, and perks without machine-readable conditions are left out
+// rather than reported as qualifying.
+func printCartPerks(ctx context.Context, cart *api.Cart) {
+	perks, err := client.GetPerks(ctx)
+	if err != nil {
+		return
+	}
+
+	var lines []string
+	for _, p := range perks {
+		if !p.HasConditions() {
+			continue
+		}
+		progress := p.Progress(cart)
+		switch {
+		case progress.Qualifies && p.IsActive:
+			lines = append(lines, fmt.Sprintf("✓ [%d] %s applies", p.ID, p.Title))
+		case progress.Qualifies:
+			lines = append(lines, fmt.Sprintf("✓ [%d] %s qualifies but is not active", p.ID, p.Title))
+		case !progress.MissingProduct && !progress.Missing.IsZero() &&
+			float64(progress.Missing.Ore) <= almostQualifies*float64(p.MinimumAmount.Ore):
+			lines = append(lines, fmt.Sprintf("… [%d] %s: add %s more", p.ID, p.Title, progress.Missing))
+		}
+	}
+
+	if len(lines) == 0 {
+		return
+	}
+
+	fmt.Println()
+	fmt.Println("Perks:")
+	for _, line := range lines {
+		fmt.Printf("  %s\n", line)
+	}
+}
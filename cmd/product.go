@@ -0,0 +1,100 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var productCmd = &cobra.Command{
+	Use:   "product [product_id]",
+	Short: "Show full product information",
+	Long: `Show everything known about a product: description, ingredients,
+nutrition, allergens, origin, labels, EAN, storage and comparison price.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		id, err := strconv.Atoi(args[0])
+		if err != nil {
+			return fmt.Errorf("invalid product ID: %w", err)
+		}
+
+		p, err := client.GetProduct(cmd.Context(), id)
+		if errors.Is(err, api.ErrNotFound) {
+			// The endpoint is unverified, so a 404 may mean it doesn't exist
+			return fmt.Errorf("failed to get product %d: %w (or the unverified product endpoint does not exist, see docs/API.md)", id, err)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get product: %w", err)
+		}
+
+		printProductDetail(p)
+		return nil
+	},
+}
+
+// printProductDetail prints a product page, skipping empty fields
+func printProductDetail(p *api.ProductDetail) {
+	name := p.FullName
+	if name == "" {
+		name = p.Name
+	}
+	fmt.Printf("[%d] %s\n", p.ID, name)
+	if p.NameExtra != "" {
+		fmt.Println(p.NameExtra)
+	}
+	fmt.Println()
+
+	field := func(label, value string) {
+		if value != "" {
+			fmt.Printf("%-14s %s\n", label+":", value)
+		}
+	}
+
+	field("Price", p.GrossPrice.String())
+	if !p.GrossUnitPrice.IsZero() && p.UnitPriceQuantityAbbr != "" {
+		field("Compare price", fmt.Sprintf("%s/%s", p.GrossUnitPrice, p.UnitPriceQuantityAbbr))
+	}
+	if p.OnOffer() {
+		field("Offer", campaignSummary(p.ProductAttributes))
+	}
+
+	availability := "✓"
+	if !p.Availability.IsAvailable {
+		availability = "✗"
+	}
+	field("Availability", strings.TrimSpace(availability+" "+p.Availability.Description))
+
+	field("Brand", p.Brand)
+	field("Origin", p.CountryOfOrigin)
+	field("Labels", strings.Join(p.Labels, ", "))
+	field("Allergens", strings.Join(p.Allergens, ", "))
+	field("EAN", p.EAN)
+	field("Storage", p.Storage)
+	if p.AbsoluteURL != "" {
+		field("URL", client.WebURL(p.AbsoluteURL))
+	}
+
+	section := func(title, text string) {
+		if text != "" {
+			fmt.Printf("\n%s:\n  %s\n", title, text)
+		}
+	}
+	section("Description", p.Description)
+	section("Ingredients", p.Ingredients)
+
+	if len(p.Nutrition.Values) > 0 {
+		fmt.Println()
+		if p.Nutrition.Basis != "" {
+			fmt.Printf("Nutrition (%s):\n", p.Nutrition.Basis)
+		} else {
+			fmt.Println("Nutrition:")
+		}
+		for _, v := range p.Nutrition.Values {
+			fmt.Printf("  %-14s %s\n", v.Name, v.Value)
+		}
+	}
+}
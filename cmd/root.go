@@ -1,16 +1,32 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
+	"net/http"
 	"os"
+	"os/signal"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/thepsadmin/mathemcli/internal/api"
 	"github.com/thepsadmin/mathemcli/internal/config"
+	"github.com/thepsadmin/mathemcli/internal/transport"
 )
 
 var (
-	client  *api.Client
+	client    *api.Client
+	timeout   time.Duration
+	retries   int
+	rateLimit float64
+	recordDir string
+	replayDir string
+	verbose   bool
+	harFile   string
+
+	// cancelTimeout releases the deadline set up for --timeout
+	cancelTimeout context.CancelFunc = func() {}
+
 	rootCmd = &cobra.Command{
 		Use:   "mathemcli",
 		Short: "CLI for interacting with the Mathem grocery API",
@@ -19,7 +35,18 @@ and managing your shopping cart on Mathem.se.
 
 Before using most commands, you need to login:
   mathemcli login`,
+		// Errors are printed once, with a hint, by Execute
+		SilenceErrors: true,
 		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Arguments are valid by now, so usage would only bury API errors
+			cmd.SilenceUsage = true
+
+			// Apply the per-command deadline before anything talks to the
+			// API. login starts it after its prompts, so typing doesn't count.
+			if cmd.Name() != "login" {
+				startTimeout(cmd)
+			}
+
 			// Skip client setup for login and help commands
 			if cmd.Name() == "login" || cmd.Name() == "help" || cmd.Name() == "version" {
 				return nil
@@ -31,28 +58,134 @@ Before using most commands, you need to login:
 				return fmt.Errorf("failed to load session: %w", err)
 			}
 
+			// Replayed traffic has its secrets scrubbed, so any session will do
+			if (session == nil || session.SessionID == "") && replayDir != "" {
+				session = &config.Session{SessionID: transport.Redacted}
+			}
+
 			if session == nil || session.SessionID == "" {
 				return fmt.Errorf("not logged in. Run 'mathemcli login' first")
 			}
 
-			client = api.NewClientWithSession(session.SessionID, session.CSRFToken)
+			opts, err := clientOptions()
+			if err != nil {
+				return err
+			}
+
+			client = api.NewClientWithSession(session.SessionID, session.CSRFToken, opts...)
 			return nil
 		},
 	}
 )
 
+// clientOptions builds API client options from global flags and the
+// MATHEMCLI_API_URL / MATHEMCLI_WEB_URL environment variables
+func clientOptions() ([]api.Option, error) {
+	policy := api.DefaultRetryPolicy
+	policy.MaxRetries = max(retries, 0)
+
+	limit := api.DefaultRateLimit
+	limit.PerSecond = rateLimit
+
+	rt, err := clientTransport()
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []api.Option{
+		api.WithRetryPolicy(policy),
+		api.WithRateLimit(limit),
+		api.WithTransport(rt),
+	}
+
+	if apiURL := os.Getenv("MATHEMCLI_API_URL"); apiURL != "" {
+		opts = append(opts, api.WithBaseURL(apiURL))
+	}
+	if webURL := os.Getenv("MATHEMCLI_WEB_URL"); webURL != "" {
+		opts = append(opts, api.WithWebBaseURL(webURL))
+	}
+
+	return opts, nil
+}
+
+// clientTransport builds the HTTP transport selected with --record/--replay,
+// wrapped for --verbose (or MATHEMCLI_DEBUG=1) and --har
+func clientTransport() (http.RoundTripper, error) {
+	var rt http.RoundTripper = http.DefaultTransport
+
+	switch {
+	case replayDir != "":
+		replayer, err := transport.NewReplayer(replayDir)
+		if err != nil {
+			return nil, err
+		}
+		rt = replayer
+	case recordDir != "":
+		recorder, err := transport.NewRecorder(rt, recordDir)
+		if err != nil {
+			return nil, err
+		}
+		rt = recorder
+	}
+
+	if harFile != "" {
+		rt = transport.NewHARRecorder(rt, harFile, Version)
+	}
+
+	if verbose || os.Getenv("MATHEMCLI_DEBUG") == "1" {
+		rt = transport.NewTracer(rt, os.Stderr)
+	}
+
+	return rt, nil
+}
+
+// startTimeout applies the --timeout deadline to the command's context
+func startTimeout(cmd *cobra.Command) {
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(cmd.Context(), timeout)
+		cmd.SetContext(ctx)
+		cancelTimeout = cancel
+	}
+}
+
 // Execute runs the root command
 func Execute() {
-	if err := rootCmd.Execute(); err != nil {
-		fmt.Fprintln(os.Stderr, err)
-		os.Exit(1)
+	// Cancel in-flight requests on Ctrl-C
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	err := rootCmd.ExecuteContext(ctx)
+	cancelTimeout()
+	stop()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "Error:", err)
+		if hint := errorHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, "Hint:", hint)
+		}
+		os.Exit(exitCode(err))
 	}
 }
 
 func init() {
+	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 0, "Overall deadline for the command, e.g. 10s (0 means no deadline)")
+	rootCmd.PersistentFlags().IntVar(&retries, "retries", api.DefaultRetryPolicy.MaxRetries, "Retries for read-only requests on timeouts, dropped connections, 429 and 5xx")
+	rootCmd.PersistentFlags().Float64Var(&rateLimit, "rate-limit", api.DefaultRateLimit.PerSecond, "Maximum requests per second to Mathem (0 means no limit)")
+	rootCmd.PersistentFlags().StringVar(&recordDir, "record", "", "Record HTTP interactions to a cassette in this directory (secrets are scrubbed)")
+	rootCmd.PersistentFlags().StringVar(&replayDir, "replay", "", "Replay HTTP interactions from a cassette in this directory instead of contacting Mathem")
+	rootCmd.MarkFlagsMutuallyExclusive("record", "replay")
+	rootCmd.PersistentFlags().BoolVarP(&verbose, "verbose", "v", false, "Log every HTTP request to stderr (also MATHEMCLI_DEBUG=1)")
+	rootCmd.PersistentFlags().StringVar(&harFile, "har", "", "Write all HTTP traffic to a HAR 1.2 file for browser devtools")
+
 	rootCmd.AddCommand(loginCmd)
 	rootCmd.AddCommand(logoutCmd)
+	rootCmd.AddCommand(whoamiCmd)
 	rootCmd.AddCommand(searchCmd)
+	rootCmd.AddCommand(suggestCmd)
+	rootCmd.AddCommand(productCmd)
+	rootCmd.AddCommand(compareCmd)
 	rootCmd.AddCommand(cartCmd)
+	rootCmd.AddCommand(dealsCmd)
+	rootCmd.AddCommand(perksCmd)
+	rootCmd.AddCommand(ordersCmd)
+	rootCmd.AddCommand(slotsCmd)
 	rootCmd.AddCommand(versionCmd)
 }
@@ -1,64 +1,110 @@
 package cmd
 
 import (
+	"context"
 	"fmt"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
 )
 
 var (
-	searchPage int
+	searchPage    int
+	searchAll     bool
+	searchLimit   int
+	searchPerPage int
 )
 
 var searchCmd = &cobra.Command{
 	Use:   "search [query]",
 	Short: "Search for products",
-	Long:  `Search for products on Mathem by name or keyword.`,
-	Args:  cobra.MinimumNArgs(1),
+	Long: `Search for products on Mathem by name or keyword.
+
+Results are shown a page at a time. Use --all to list every result, or
+--limit to list up to a number of results, fetching pages as needed.
+
+Filters and --sort apply to the page shown, or across all pages with --all
+or --limit. For example, the ten cheapest available coffees per kilo:
+
+  mathemcli search kaffe --available-only --sort unit-price --limit 10
+
+With --file, every line of the file (or stdin for -) is searched, several
+at a time, and the results are printed per query in the file's order:
+
+  mathemcli search -f shopping-list.txt --available-only --sort price --limit 3`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if searchFile != "" {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.MinimumNArgs(1)(cmd, args)
+	},
 	RunE: func(cmd *cobra.Command, args []string) error {
 		query := strings.Join(args, " ")
 
-		result, err := client.Search(query, searchPage)
+		if searchLimit < 0 {
+			return fmt.Errorf("invalid --limit %d", searchLimit)
+		}
+		if searchPerPage < 0 {
+			return fmt.Errorf("invalid --per-page %d", searchPerPage)
+		}
+
+		filter, err := parseProductFilter()
+		if err != nil {
+			return err
+		}
+		order, err := productOrder(searchSort)
+		if err != nil {
+			return err
+		}
+
+		var opts []api.SearchOption
+		if searchPerPage > 0 {
+			opts = append(opts, api.WithPerPage(searchPerPage))
+		}
+
+		if searchFile != "" {
+			return searchBatch(cmd.Context(), searchFile, filter, order, opts...)
+		}
+
+		if searchAll || searchLimit > 0 {
+			return searchStream(cmd.Context(), query, filter, order, searchLimit, opts...)
+		}
+
+		result, err := client.Search(cmd.Context(), query, searchPage, opts...)
 		if err != nil {
 			return fmt.Errorf("search failed: %w", err)
 		}
 
 		if len(result.Items) == 0 {
 			fmt.Println("No products found")
+			printDidYouMean(cmd.Context(), query)
 			return nil
 		}
 
-		fmt.Printf("Found %d products (page %d):\n\n",
-			result.Attributes.Items, result.Attributes.Page)
-
+		var products []api.Product
 		for _, item := range result.Items {
-			if item.Type != "product" {
-				continue
-			}
-			attr := item.Attributes
-			availability := "✓"
-			if !attr.Availability.IsAvailable {
-				availability = "✗"
+			if item.Type == "product" && filter.match(item) {
+				products = append(products, item)
 			}
+		}
+		sortProducts(products, order)
 
-			fmt.Printf("[%d] %s %s\n", item.ID, availability, attr.Name)
-			if attr.Brand != "" {
-				fmt.Printf("     Brand: %s\n", attr.Brand)
-			}
-			if attr.NameExtra != "" {
-				fmt.Printf("     %s\n", attr.NameExtra)
-			}
-			fmt.Printf("     Price: %s %s", attr.GrossPrice, attr.Currency)
-			if attr.GrossUnitPrice != "" && attr.UnitPriceQuantityAbbr != "" {
-				fmt.Printf(" (%s/%s)", attr.GrossUnitPrice, attr.UnitPriceQuantityAbbr)
-			}
-			fmt.Println()
-			fmt.Println()
+		if filter.active() {
+			fmt.Printf("Found %d products (page %d), %d match the filters:\n\n",
+				result.Attributes.Items, result.Attributes.Page, len(products))
+		} else {
+			fmt.Printf("Found %d products (page %d):\n\n",
+				result.Attributes.Items, result.Attributes.Page)
+		}
+
+		for _, item := range products {
+			printProduct(item)
 		}
 
 		if result.Attributes.HasMoreItems {
-			fmt.Printf("More results available. Use --page %d to see next page.\n",
+			fmt.Printf("More results available. Use --page %d to see next page, or --all to search every page.\n",
 				searchPage+1)
 		}
 
@@ -66,6 +112,151 @@ var searchCmd = &cobra.Command{
 	},
 }
 
+// searchStream prints every result of a search that passes the filter, or
+// the first limit of them if limit is positive, fetching pages as needed.
+// Sorting needs every result, so all pages are fetched before any are
+// printed; otherwise results are printed as they arrive.
+func searchStream(ctx context.Context, query string, filter productFilter, order func(a, b api.Product) int, limit int, opts ...api.SearchOption) error {
+	var found, count int
+	var sorted []api.Product
+	for item, err := range client.SearchAll(ctx, query, opts...) {
+		if err != nil {
+			return fmt.Errorf("search failed: %w", err)
+		}
+
+		found++
+		if !filter.match(item) {
+			continue
+		}
+
+		if order != nil {
+			sorted = append(sorted, item)
+			continue
+		}
+
+		printProduct(item)
+		count++
+		if count == limit {
+			break
+		}
+	}
+
+	if order != nil {
+		sortProducts(sorted, order)
+		if limit > 0 && len(sorted) > limit {
+			sorted = sorted[:limit]
+		}
+		for _, item := range sorted {
+			printProduct(item)
+		}
+		count = len(sorted)
+	}
+
+	switch {
+	case found == 0:
+		fmt.Println("No products found")
+		printDidYouMean(ctx, query)
+	case count == 0:
+		fmt.Println("No products match the filters")
+	default:
+		fmt.Printf("Showed %d products\n", count)
+	}
+	return nil
+}
+
+// maxDidYouMean is how many suggestions are offered for an empty search
+const maxDidYouMean = 3
+
+// printDidYouMean suggests other spellings after a search came back empty.
+// It is only a hint, so errors are ignored.
+func printDidYouMean(ctx context.Context, query string) {
+	suggestions, err := client.Suggest(ctx, query)
+	if err != nil {
+		return
+	}
+
+	var terms []string
+	for _, s := range suggestions {
+		if text := s.Attributes.Text; text != "" && !strings.EqualFold(text, query) {
+			terms = append(terms, text)
+		}
+		if len(terms) == maxDidYouMean {
+			break
+		}
+	}
+
+	if len(terms) > 0 {
+		fmt.Printf("Did you mean: %s?\n", strings.Join(terms, ", "))
+	}
+}
+
+// printProduct prints a product as listed in search results
+func printProduct(item api.Product) {
+	attr := item.Attributes
+	availability := "✓"
+	if !attr.Availability.IsAvailable {
+		availability = "✗"
+	}
+
+	fmt.Printf("[%d] %s %s\n", item.ID, availability, attr.Name)
+	if attr.Brand != "" {
+		fmt.Printf("     Brand: %s\n", attr.Brand)
+	}
+	if attr.NameExtra != "" {
+		fmt.Printf("     %s\n", attr.NameExtra)
+	}
+	fmt.Printf("     Price: %s", attr.GrossPrice)
+	if !attr.GrossUnitPrice.IsZero() && attr.UnitPriceQuantityAbbr != "" {
+		fmt.Printf(" (%s/%s)", attr.GrossUnitPrice, attr.UnitPriceQuantityAbbr)
+	}
+	fmt.Println()
+	if attr.OnOffer() {
+		fmt.Printf("     Offer: %s\n", campaignSummary(attr))
+	}
+	fmt.Println()
+}
+
+// campaignSummary describes a product's offer, e.g.
+// "2 för 110 kr (save 15%), until 2026-10-24"
+func campaignSummary(attr api.ProductAttributes) string {
+	c := attr.Campaign
+
+	summary := c.DiscountLabel
+	if summary == "" {
+		summary = c.Name
+	}
+
+	var details []string
+	if !c.OriginalPrice.IsZero() && c.MultiBuy == nil {
+		details = append(details, "was "+c.OriginalPrice.String())
+	}
+	if pct := attr.DiscountPercent(); pct > 0 && !strings.Contains(summary, "%") {
+		details = append(details, fmt.Sprintf("save %d%%", pct))
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, ", ") + ")"
+	}
+
+	if !c.ValidUntil.IsZero() {
+		summary += ", until " + c.ValidUntil.Format(time.DateOnly)
+	}
+	return summary
+}
+
 func init() {
 	searchCmd.Flags().IntVarP(&searchPage, "page", "n", 1, "Page number")
+	searchCmd.Flags().BoolVarP(&searchAll, "all", "a", false, "Show all results, fetching every page")
+	searchCmd.Flags().IntVarP(&searchLimit, "limit", "l", 0, "Show up to this many results, fetching pages as needed")
+	searchCmd.Flags().IntVar(&searchPerPage, "per-page", 0, "Results per page (default: the API's page size)")
+	searchCmd.Flags().BoolVar(&searchAvailableOnly, "available-only", false, "Only show products in stock")
+	searchCmd.Flags().StringVarP(&searchBrand, "brand", "b", "", "Only show products from this brand")
+	searchCmd.Flags().StringVar(&searchMaxPrice, "max-price", "", "Only show products costing up to this amount, e.g. 49,90")
+	searchCmd.Flags().StringVar(&searchMaxUnitPrice, "max-unit-price", "", "Only show products costing up to this amount per kg, l or st (other units are converted)")
+	searchCmd.Flags().StringSliceVar(&searchExclude, "exclude", nil, "Hide products whose name contains this word (repeatable)")
+	searchCmd.Flags().BoolVar(&searchOnOffer, "on-offer", false, "Only show products on offer")
+	searchCmd.Flags().StringVarP(&searchSort, "sort", "s", "", "Sort by price, unit-price, name or brand")
+	searchCmd.Flags().StringVarP(&searchFile, "file", "f", "", "Search every line of this file, or stdin for -")
+	searchCmd.Flags().IntVarP(&searchConcurrency, "concurrency", "c", defaultSearchConcurrency, "Searches to run at once with --file")
+	searchCmd.MarkFlagsMutuallyExclusive("page", "all")
+	searchCmd.MarkFlagsMutuallyExclusive("page", "limit")
 }
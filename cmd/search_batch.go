@@ -0,0 +1,177 @@
+package cmd
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var (
+	searchFile        string
+	searchConcurrency int
+)
+
+// defaultSearchConcurrency is how many searches from a file run at once
+const defaultSearchConcurrency = 4
+
+// searchBatchResult is the outcome of one query from a file
+type searchBatchResult struct {
+	products []api.Product
+	found    int
+	more     bool
+	err      error
+}
+
+// readQueries reads one query per line from a file, or from stdin for "-".
+// Blank lines and lines starting with # are skipped.
+func readQueries(name string) ([]string, error) {
+	var r io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read queries: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var queries []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		queries = append(queries, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read queries: %w", err)
+	}
+
+	return queries, nil
+}
+
+// searchBatch runs the queries in a file on a pool of workers and prints
+// the results of each query in input order, as soon as it and every query
+// before it are done. The client's rate limit applies across all workers.
+func searchBatch(ctx context.Context, name string, filter productFilter, order func(a, b api.Product) int, opts ...api.SearchOption) error {
+	if searchConcurrency < 1 {
+		return fmt.Errorf("invalid --concurrency %d", searchConcurrency)
+	}
+
+	queries, err := readQueries(name)
+	if err != nil {
+		return err
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("no queries in %s", name)
+	}
+
+	// One buffered channel per query lets workers finish in any order
+	results := make([]chan searchBatchResult, len(queries))
+	for i := range results {
+		results[i] = make(chan searchBatchResult, 1)
+	}
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for range min(searchConcurrency, len(queries)) {
+		wg.Go(func() {
+			for i := range jobs {
+				results[i] <- searchOne(ctx, queries[i], filter, order, opts...)
+			}
+		})
+	}
+	go func() {
+		for i := range queries {
+			jobs <- i
+		}
+		close(jobs)
+	}()
+	defer wg.Wait()
+
+	failed := 0
+	for i, query := range queries {
+		result := <-results[i]
+
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("=== %s ===\n\n", query)
+
+		switch {
+		case result.err != nil:
+			fmt.Printf("Search failed: %v\n", result.err)
+			failed++
+			continue
+		case result.found == 0:
+			fmt.Println("No products found")
+			continue
+		case len(result.products) == 0:
+			fmt.Println("No products match the filters")
+			continue
+		}
+
+		for _, item := range result.products {
+			printProduct(item)
+		}
+		if result.more {
+			fmt.Println("More results available. Use --all or --limit to see more.")
+		}
+	}
+
+	if failed > 0 {
+		return fmt.Errorf("%d of %d searches failed", failed, len(queries))
+	}
+	return nil
+}
+
+// searchOne runs one query of a batch the way a single search would: the
+// page from --page, or every page with --all or --limit
+func searchOne(ctx context.Context, query string, filter productFilter, order func(a, b api.Product) int, opts ...api.SearchOption) searchBatchResult {
+	var result searchBatchResult
+
+	if searchAll || searchLimit > 0 {
+		for item, err := range client.SearchAll(ctx, query, opts...) {
+			if err != nil {
+				return searchBatchResult{err: err}
+			}
+			result.found++
+			if filter.match(item) {
+				result.products = append(result.products, item)
+			}
+			// Without sorting, later pages can't change the first results
+			if order == nil && searchLimit > 0 && len(result.products) == searchLimit {
+				break
+			}
+		}
+	} else {
+		page, err := client.Search(ctx, query, searchPage, opts...)
+		if err != nil {
+			return searchBatchResult{err: err}
+		}
+		for _, item := range page.Items {
+			if item.Type != "product" {
+				continue
+			}
+			result.found++
+			if filter.match(item) {
+				result.products = append(result.products, item)
+			}
+		}
+		result.more = page.Attributes.HasMoreItems
+	}
+
+	sortProducts(result.products, order)
+	if searchLimit > 0 && len(result.products) > searchLimit {
+		result.products = result.products[:searchLimit]
+	}
+
+	return result
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var (
+	searchAvailableOnly bool
+	searchBrand         string
+	searchMaxPrice      string
+	searchMaxUnitPrice  string
+	searchExclude       []string
+	searchOnOffer       bool
+	searchSort          string
+)
+
+// productFilter holds the parsed search filter flags
+type productFilter struct {
+	availableOnly bool
+	brand         string
+	maxPrice      *api.Money
+	maxUnitPrice  *api.Money
+	exclude       []string
+	onOffer       bool
+}
+
+// parseProductFilter validates the search filter flags
+func parseProductFilter() (productFilter, error) {
+	f := productFilter{
+		availableOnly: searchAvailableOnly,
+		brand:         searchBrand,
+		onOffer:       searchOnOffer,
+	}
+
+	if searchMaxPrice != "" {
+		price, err := api.ParseMoney(searchMaxPrice)
+		if err != nil {
+			return f, fmt.Errorf("invalid max price: %w", err)
+		}
+		f.maxPrice = &price
+	}
+
+	if searchMaxUnitPrice != "" {
+		price, err := api.ParseMoney(searchMaxUnitPrice)
+		if err != nil {
+			return f, fmt.Errorf("invalid max unit price: %w", err)
+		}
+		f.maxUnitPrice = &price
+	}
+
+	for _, word := range searchExclude {
+		if word = strings.TrimSpace(word); word != "" {
+			f.exclude = append(f.exclude, strings.ToLower(word))
+		}
+	}
+
+	return f, nil
+}
+
+// active reports whether any filter is set
+func (f productFilter) active() bool {
+	return f.availableOnly || f.brand != "" || f.maxPrice != nil ||
+		f.maxUnitPrice != nil || len(f.exclude) > 0 || f.onOffer
+}
+
+// match reports whether a product passes the filters. --max-unit-price
+// compares prices per kg, l or st, so kr/hg and the like are converted
+// first; products without a unit price in one of those never pass it.
+func (f productFilter) match(p api.Product) bool {
+	attr := p.Attributes
+
+	if f.availableOnly && !attr.Availability.IsAvailable {
+		return false
+	}
+	if f.brand != "" && !strings.EqualFold(attr.Brand, f.brand) {
+		return false
+	}
+	if f.maxPrice != nil && f.maxPrice.Less(attr.GrossPrice) {
+		return false
+	}
+	if f.maxUnitPrice != nil {
+		up, ok := normalizedUnitPrice(p)
+		if !ok || f.maxUnitPrice.Less(up.price) {
+			return false
+		}
+	}
+	if f.onOffer && !attr.OnOffer() {
+		return false
+	}
+
+	if len(f.exclude) > 0 {
+		text := strings.ToLower(attr.FullName + " " + attr.Name + " " + attr.NameExtra)
+		for _, word := range f.exclude {
+			if strings.Contains(text, word) {
+				return false
+			}
+		}
+	}
+
+	return true
+}
+
+// productOrder returns the comparison for the --sort flag, or nil to keep
+// the search order
+func productOrder(sort string) (func(a, b api.Product) int, error) {
+	switch sort {
+	case "":
+		return nil, nil
+	case "price":
+		return func(a, b api.Product) int {
+			return a.Attributes.GrossPrice.Cmp(b.Attributes.GrossPrice)
+		}, nil
+	case "unit-price":
+		// Prices per kg come first, then per l and per st. Products without
+		// a unit price that converts to one of those go last.
+		return func(a, b api.Product) int {
+			ua, okA := normalizedUnitPrice(a)
+			ub, okB := normalizedUnitPrice(b)
+			switch {
+			case !okA || !okB:
+				return cmp.Compare(boolInt(!okA), boolInt(!okB))
+			case ua.unit != ub.unit:
+				return cmp.Compare(baseUnitRank[ua.unit], baseUnitRank[ub.unit])
+			}
+			return ua.price.Cmp(ub.price)
+		}, nil
+	case "name":
+		return func(a, b api.Product) int {
+			return cmp.Compare(strings.ToLower(a.Attributes.Name), strings.ToLower(b.Attributes.Name))
+		}, nil
+	case "brand":
+		return func(a, b api.Product) int {
+			return cmp.Or(
+				cmp.Compare(strings.ToLower(a.Attributes.Brand), strings.ToLower(b.Attributes.Brand)),
+				cmp.Compare(strings.ToLower(a.Attributes.Name), strings.ToLower(b.Attributes.Name)),
+			)
+		}, nil
+	}
+	return nil, fmt.Errorf("invalid --sort %q, use price, unit-price, name or brand", sort)
+}
+
+// sortProducts sorts products in place, keeping the search order for ties
+func sortProducts(products []api.Product, order func(a, b api.Product) int) {
+	if order != nil {
+		slices.SortStableFunc(products, order)
+	}
+}
+
+func boolInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,271 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+var (
+	slotsDays   int
+	slotsDate   string
+	slotsAfter  string
+	slotsMaxFee string
+)
+
+var slotsCmd = &cobra.Command{
+	Use:   "slots",
+	Short: "Browse delivery time slots",
+	Long: `List the delivery windows for the coming days with their fee,
+availability and eco flag.
+
+Filter with --date 2026-10-18, --after 17:00 or --max-fee 39.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		filter, days, err := parseSlotFilter()
+		if err != nil {
+			return err
+		}
+
+		result, err := client.GetDeliverySlots(cmd.Context(), days)
+		if err != nil {
+			return fmt.Errorf("failed to get delivery slots: %w", err)
+		}
+
+		found := false
+		for _, day := range result.Days {
+			if filter.date != "" && day.Date != filter.date {
+				continue
+			}
+
+			var slots []api.DeliverySlot
+			for _, slot := range day.Slots {
+				if filter.match(slot) {
+					slots = append(slots, slot)
+				}
+			}
+			if len(slots) == 0 {
+				continue
+			}
+
+			if found {
+				fmt.Println()
+			}
+			found = true
+
+			fmt.Println(dayHeading(day))
+			for _, slot := range slots {
+				printSlot(slot)
+			}
+		}
+
+		if !found {
+			fmt.Println("No delivery slots found")
+		}
+
+		return nil
+	},
+}
+
+// noReservation explains a 404 from the reservation endpoints. They are
+// unverified, so the 404 may mean they don't exist rather than that no
+// slot is held.
+const noReservation = "No delivery slot reserved (or the unverified reservation endpoint does not exist, see docs/API.md)"
+
+var slotsReserveCmd = &cobra.Command{
+	Use:   "reserve [slot_id]",
+	Short: "Reserve a delivery slot",
+	Long: `Hold a delivery slot while you fill the cart. Get the ID from
+'mathemcli slots'. Reserving replaces any slot you already hold.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reservation, err := client.ReserveSlot(cmd.Context(), args[0])
+		if err != nil {
+			return fmt.Errorf("failed to reserve slot: %w", err)
+		}
+
+		fmt.Println("Slot reserved")
+		printReservation(reservation)
+		return nil
+	},
+}
+
+var slotsCurrentCmd = &cobra.Command{
+	Use:   "current",
+	Short: "Show the reserved delivery slot",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		reservation, err := client.GetSlotReservation(cmd.Context())
+		if errors.Is(err, api.ErrNotFound) {
+			fmt.Println(noReservation)
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get reserved slot: %w", err)
+		}
+
+		printReservation(reservation)
+		return nil
+	},
+}
+
+var slotsReleaseCmd = &cobra.Command{
+	Use:   "release",
+	Short: "Release the reserved delivery slot",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if err := client.ReleaseSlot(cmd.Context()); err != nil {
+			if errors.Is(err, api.ErrNotFound) {
+				fmt.Println(noReservation)
+				return nil
+			}
+			return fmt.Errorf("failed to release slot: %w", err)
+		}
+
+		fmt.Println("Slot released")
+		return nil
+	},
+}
+
+// slotFilter holds the parsed --date, --after and --max-fee filters
+type slotFilter struct {
+	date   string
+	after  time.Duration
+	maxFee *api.Money
+}
+
+// parseSlotFilter validates the filter flags and returns how many days to
+// fetch, which is extended to reach the --date if necessary
+func parseSlotFilter() (slotFilter, int, error) {
+	var f slotFilter
+	days := max(slotsDays, 1)
+
+	if slotsDate != "" {
+		date, err := time.ParseInLocation(time.DateOnly, slotsDate, time.Local)
+		if err != nil {
+			return f, 0, fmt.Errorf("invalid date %q, use YYYY-MM-DD", slotsDate)
+		}
+
+		now := time.Now()
+		today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.Local)
+		if date.Before(today) {
+			return f, 0, fmt.Errorf("date %s is in the past", slotsDate)
+		}
+		// Round, since a day across a DST change isn't 24 hours
+		ahead := int(date.Sub(today).Hours()/24 + 0.5)
+
+		f.date = date.Format(time.DateOnly)
+		days = max(days, ahead+1)
+	}
+
+	if slotsAfter != "" {
+		t, err := time.Parse("15:04", slotsAfter)
+		if err != nil {
+			return f, 0, fmt.Errorf("invalid time %q, use HH:MM", slotsAfter)
+		}
+		f.after = clock(t)
+	}
+
+	if slotsMaxFee != "" {
+		fee, err := api.ParseMoney(slotsMaxFee)
+		if err != nil {
+			return f, 0, fmt.Errorf("invalid max fee: %w", err)
+		}
+		f.maxFee = &fee
+	}
+
+	return f, days, nil
+}
+
+// match reports whether a slot passes the time and fee filters
+func (f slotFilter) match(slot api.DeliverySlot) bool {
+	if clock(slot.StartTime) < f.after {
+		return false
+	}
+	if f.maxFee != nil && f.maxFee.Less(slot.DeliveryFee) {
+		return false
+	}
+	return true
+}
+
+// clock returns the time of day of t in its own location
+func clock(t time.Time) time.Duration {
+	return time.Duration(t.Hour())*time.Hour + time.Duration(t.Minute())*time.Minute
+}
+
+// dayHeading formats a delivery day, e.g. "Saturday 2026-10-18"
+func dayHeading(day api.DeliveryDay) string {
+	date, err := time.Parse(time.DateOnly, day.Date)
+	if err != nil {
+		return day.Date
+	}
+	return date.Format("Monday 2006-01-02")
+}
+
+// printSlot prints one delivery window as a table row
+func printSlot(slot api.DeliverySlot) {
+	availability := "✓"
+	if !slot.IsAvailable {
+		availability = "✗"
+	}
+
+	fee := slot.DeliveryFee.String()
+	if slot.DeliveryFee.IsZero() {
+		fee = "free"
+	}
+
+	fmt.Printf("  [%s] %s %s–%s %12s",
+		slot.ID, availability,
+		slot.StartTime.Format("15:04"), slot.EndTime.Format("15:04"),
+		fee)
+	if slot.IsEco {
+		fmt.Print("  eco")
+	}
+	fmt.Println()
+}
+
+// printReservation prints the reserved window and how long it is held
+func printReservation(r *api.SlotReservation) {
+	slot := r.Slot
+	fmt.Printf("[%s] %s %s–%s, %s",
+		slot.ID, slot.StartTime.Format("Monday 2006-01-02"),
+		slot.StartTime.Format("15:04"), slot.EndTime.Format("15:04"),
+		slot.DeliveryFee)
+	if slot.IsEco {
+		fmt.Print(", eco")
+	}
+	fmt.Println()
+
+	left := time.Until(r.ExpiresAt).Round(time.Minute)
+	if left <= 0 {
+		fmt.Println("The reservation has expired")
+		return
+	}
+	fmt.Printf("Held for %s (until %s)\n", formatHold(left), r.ExpiresAt.Local().Format("15:04"))
+}
+
+// formatHold formats a remaining hold time, e.g. "1 h 5 min"
+func formatHold(d time.Duration) string {
+	h, m := int(d.Hours()), int(d.Minutes())%60
+	switch {
+	case h == 0:
+		return fmt.Sprintf("%d min", m)
+	case m == 0:
+		return fmt.Sprintf("%d h", h)
+	}
+	return fmt.Sprintf("%d h %d min", h, m)
+}
+
+func init() {
+	slotsCmd.AddCommand(slotsReserveCmd)
+	slotsCmd.AddCommand(slotsCurrentCmd)
+	slotsCmd.AddCommand(slotsReleaseCmd)
+
+	slotsCmd.Flags().IntVarP(&slotsDays, "days", "d", 3, "Number of days to show")
+	slotsCmd.Flags().StringVar(&slotsDate, "date", "", "Only show slots on this date (YYYY-MM-DD)")
+	slotsCmd.Flags().StringVar(&slotsAfter, "after", "", "Only show slots starting at or after this time (HH:MM)")
+	slotsCmd.Flags().StringVar(&slotsMaxFee, "max-fee", "", "Only show slots with a delivery fee up to this amount, e.g. 39")
+}
@@ -0,0 +1,43 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+var suggestCmd = &cobra.Command{
+	Use:   "suggest [prefix]",
+	Short: "Suggest search terms",
+	Long: `Complete a search term, or find the right spelling of one, using
+Mathem's autocomplete.`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		prefix := strings.Join(args, " ")
+
+		suggestions, err := client.Suggest(cmd.Context(), prefix)
+		if err != nil {
+			return fmt.Errorf("suggest failed: %w", err)
+		}
+
+		// Skip suggestions without text, e.g. from a response shaped
+		// differently than expected
+		var terms []string
+		for _, s := range suggestions {
+			if s.Attributes.Text != "" {
+				terms = append(terms, s.Attributes.Text)
+			}
+		}
+
+		if len(terms) == 0 {
+			fmt.Println("No suggestions found")
+			return nil
+		}
+
+		for _, term := range terms {
+			fmt.Println(term)
+		}
+		return nil
+	},
+}
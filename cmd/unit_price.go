@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"strings"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+	"github.com/thepsadmin/mathemcli/internal/packsize"
+)
+
+// unitPriced is a product with its price per kg, l or st
+type unitPriced struct {
+	product   api.Product
+	price     api.Money
+	unit      string
+	estimated bool
+}
+
+// unitPrice returns a product's price per kg, l or st. The API's unit price
+// is used if there is one; otherwise it is computed from the pack size in
+// NameExtra. Unit prices in units that can't be converted are kept as they
+// are, so they end up in a group of their own.
+func unitPrice(p api.Product) (unitPriced, bool) {
+	attr := p.Attributes
+
+	if !attr.GrossUnitPrice.IsZero() && attr.UnitPriceQuantityAbbr != "" {
+		unit, err := packsize.ParseUnit(attr.UnitPriceQuantityAbbr)
+		if err != nil {
+			return unitPriced{product: p, price: attr.GrossUnitPrice, unit: strings.ToLower(attr.UnitPriceQuantityAbbr)}, true
+		}
+		one := packsize.Quantity{Value: 1, Unit: unit}.Base()
+		return unitPriced{product: p, price: attr.GrossUnitPrice.Div(one.Value), unit: string(one.Unit)}, true
+	}
+
+	if attr.GrossPrice.IsZero() {
+		return unitPriced{}, false
+	}
+	size, err := packsize.Parse(attr.NameExtra)
+	if err != nil {
+		return unitPriced{}, false
+	}
+	total := size.Total().Base()
+	return unitPriced{product: p, price: attr.GrossPrice.Div(total.Value), unit: string(total.Unit), estimated: true}, true
+}
+
+// baseUnitRank orders the units unit prices are normalized to. Prices in
+// other units can't be compared with them.
+var baseUnitRank = map[string]int{
+	string(packsize.Kilogram): 0,
+	string(packsize.Litre):    1,
+	string(packsize.Piece):    2,
+}
+
+// normalizedUnitPrice returns a product's price per kg, l or st, and false
+// if it has none or it is in a unit that can't be converted
+func normalizedUnitPrice(p api.Product) (unitPriced, bool) {
+	up, ok := unitPrice(p)
+	if !ok {
+		return unitPriced{}, false
+	}
+	if _, ok := baseUnitRank[up.unit]; !ok {
+		return unitPriced{}, false
+	}
+	return up, true
+}
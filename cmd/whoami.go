@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/thepsadmin/mathemcli/internal/api"
+	"github.com/thepsadmin/mathemcli/internal/config"
+)
+
+var whoamiCmd = &cobra.Command{
+	Use:     "whoami",
+	Aliases: []string{"status"},
+	Short:   "Show the logged-in account and check the session",
+	Long: `Show the account you logged in as, when the session was created and
+roughly when it expires, and check that Mathem still accepts it by
+fetching the cart.
+
+The account is the email you logged in with. Member and customer numbers
+are not shown, since no endpoint for them is known yet.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		session, err := config.LoadSession()
+		if err != nil {
+			return fmt.Errorf("failed to load session: %w", err)
+		}
+
+		// The cart is the cheapest endpoint known to require a session
+		_, checkErr := client.GetCart(cmd.Context())
+		if checkErr != nil && !errors.Is(checkErr, api.ErrSessionExpired) {
+			return fmt.Errorf("failed to check session: %w", checkErr)
+		}
+
+		field := func(label, value string) {
+			if value != "" {
+				fmt.Printf("%-12s %s\n", label+":", value)
+			}
+		}
+
+		if session != nil {
+			field("Account", session.Email)
+			if session.CreatedAt.IsZero() {
+				field("Logged in", "unknown")
+			} else {
+				field("Logged in", session.CreatedAt.Local().Format("2006-01-02 15:04"))
+				field("Expires", sessionExpiry(session.ExpiresAt()))
+			}
+		}
+
+		if checkErr != nil {
+			field("Session", "rejected by Mathem")
+			return fmt.Errorf("session is no longer valid: %w", checkErr)
+		}
+		field("Session", "valid")
+		return nil
+	},
+}
+
+// sessionExpiry formats the estimated expiry with the days left
+func sessionExpiry(expires time.Time) string {
+	left := time.Until(expires)
+	date := expires.Local().Format(time.DateOnly)
+	if left <= 0 {
+		return date + " (estimated, already passed)"
+	}
+	return fmt.Sprintf("~%s (in about %d days)", date, int(left.Hours()/24+0.5))
+}
@@ -2,15 +2,19 @@ package api
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/cookiejar"
 	"net/url"
+	"slices"
+	"strings"
 	"time"
 )
 
+// Defaults used unless overridden with an Option
 const (
 	BaseURL     = "https://www.mathem.se/tienda-web-api/v1"
 	WebBaseURL  = "https://www.mathem.se"
@@ -20,47 +24,114 @@ const (
 	UserAgent = "Mozilla/5.0 (X11; Linux x86_64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/144.0.0.0 Safari/537.36"
 )
 
-// Client handles communication with the Mathem API
+// Session and CSRF cookie names
+const (
+	sessionCookie = "sessionid"
+	csrfCookie    = "csrftoken"
+)
+
+// Client handles communication with the Mathem API. It is safe for
+// concurrent use: the session lives in the HTTP client's cookie jar, the
+// rate limiter has a lock of its own, and nothing else changes after
+// construction.
 type Client struct {
 	httpClient *http.Client
+	transport  http.RoundTripper
+	timeout    *time.Duration
 	baseURL    string
-	sessionID  string
-	csrfToken  string
+	webBaseURL string
+	userAgent  string
+	retry      RetryPolicy
+	rateLimit  RateLimit
+	limiter    *rateLimiter
 }
 
 // NewClient creates a new API client
-func NewClient() *Client {
+func NewClient(opts ...Option) *Client {
 	jar, _ := cookiejar.New(nil)
-	return &Client{
+	c := &Client{
 		httpClient: &http.Client{
 			Timeout: 30 * time.Second,
 			Jar:     jar,
 		},
-		baseURL: BaseURL,
+		baseURL:    BaseURL,
+		webBaseURL: WebBaseURL,
+		userAgent:  UserAgent,
+		retry:      DefaultRetryPolicy,
+		rateLimit:  DefaultRateLimit,
+	}
+
+	for _, opt := range opts {
+		opt(c)
+	}
+	// Applied last so WithHTTPClient can't replace them, whatever the order
+	if c.transport != nil {
+		c.httpClient.Transport = c.transport
 	}
+	if c.timeout != nil {
+		c.httpClient.Timeout = *c.timeout
+	}
+	c.limiter = newRateLimiter(c.rateLimit)
+
+	return c
 }
 
 // NewClientWithSession creates a client with an existing session
-func NewClientWithSession(sessionID, csrfToken string) *Client {
-	client := NewClient()
-	client.sessionID = sessionID
-	client.csrfToken = csrfToken
+func NewClientWithSession(sessionID, csrfToken string, opts ...Option) *Client {
+	client := NewClient(opts...)
+
+	var cookies []*http.Cookie
+	if sessionID != "" {
+		cookies = append(cookies, &http.Cookie{Name: sessionCookie, Value: sessionID, Path: "/"})
+	}
+	if csrfToken != "" {
+		cookies = append(cookies, &http.Cookie{Name: csrfCookie, Value: csrfToken, Path: "/"})
+	}
+
+	// Seed both hosts in case the API and website are served separately
+	for _, raw := range []string{client.baseURL, client.webBaseURL} {
+		if u, err := url.Parse(raw); err == nil {
+			client.httpClient.Jar.SetCookies(u, cookies)
+		}
+	}
+
 	return client
 }
 
 // SessionID returns the current session ID
 func (c *Client) SessionID() string {
-	return c.sessionID
+	return c.cookie(sessionCookie)
 }
 
 // CSRFToken returns the current CSRF token
 func (c *Client) CSRFToken() string {
-	return c.csrfToken
+	return c.cookie(csrfCookie)
+}
+
+// cookie returns the value the jar would send to the API for a cookie
+func (c *Client) cookie(name string) string {
+	u, err := url.Parse(c.baseURL + "/")
+	if err != nil {
+		return ""
+	}
+
+	for _, cookie := range c.httpClient.Jar.Cookies(u) {
+		if cookie.Name == name {
+			return cookie.Value
+		}
+	}
+	return ""
+}
+
+// WebURL returns the website URL for a path such as a product's
+// AbsoluteURL
+func (c *Client) WebURL(path string) string {
+	return c.webBaseURL + path
 }
 
 // setBrowserHeaders adds browser-like headers to mimic Chrome
 func (c *Client) setBrowserHeaders(req *http.Request, referer string) {
-	req.Header.Set("User-Agent", UserAgent)
+	req.Header.Set("User-Agent", c.userAgent)
 	req.Header.Set("Accept", "application/json, text/plain, */*")
 	req.Header.Set("Accept-Language", "en-US,en;q=0.9,sv;q=0.8")
 	req.Header.Set("sec-ch-ua", `"Not(A:Brand";v="8", "Chromium";v="144", "Google Chrome";v="144"`)
@@ -75,61 +146,62 @@ func (c *Client) setBrowserHeaders(req *http.Request, referer string) {
 	}
 }
 
-// doRequest performs an HTTP request with proper headers
-func (c *Client) doRequest(method, endpoint string, body any, referer string) (*http.Response, error) {
-	var reqBody io.Reader
+// doRequest performs an HTTP request with proper headers. Every attempt
+// waits for the rate limiter. Safe requests are retried on transient
+// failures according to the client's retry policy.
+func (c *Client) doRequest(ctx context.Context, method, endpoint string, body any, referer string) (*http.Response, error) {
+	var jsonBody []byte
 	if body != nil {
-		jsonBody, err := json.Marshal(body)
+		var err error
+		jsonBody, err = json.Marshal(body)
 		if err != nil {
 			return nil, fmt.Errorf("failed to marshal request body: %w", err)
 		}
-		reqBody = bytes.NewBuffer(jsonBody)
 	}
 
-	reqURL := c.baseURL + endpoint
-	req, err := http.NewRequest(method, reqURL, reqBody)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+	policy := c.retry
+	if !isSafeMethod(method) {
+		policy = NoRetries
 	}
 
-	c.setBrowserHeaders(req, referer)
+	reqURL := c.baseURL + endpoint
+	for attempt := 0; ; attempt++ {
+		if err := c.limiter.wait(ctx); err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
+		}
 
-	if body != nil {
-		req.Header.Set("Content-Type", ContentType)
-	}
+		var reqBody io.Reader
+		if body != nil {
+			reqBody = bytes.NewReader(jsonBody)
+		}
 
-	// Add session cookie if available
-	if c.sessionID != "" {
-		req.AddCookie(&http.Cookie{
-			Name:  "sessionid",
-			Value: c.sessionID,
-		})
-	}
+		req, err := http.NewRequestWithContext(ctx, method, reqURL, reqBody)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create request: %w", err)
+		}
 
-	// Add CSRF token if available
-	if c.csrfToken != "" {
-		req.AddCookie(&http.Cookie{
-			Name:  "csrftoken",
-			Value: c.csrfToken,
-		})
-	}
+		c.setBrowserHeaders(req, referer)
 
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return nil, fmt.Errorf("request failed: %w", err)
-	}
+		if body != nil {
+			req.Header.Set("Content-Type", ContentType)
+		}
 
-	// Extract cookies from response
-	for _, cookie := range resp.Cookies() {
-		switch cookie.Name {
-		case "sessionid":
-			c.sessionID = cookie.Value
-		case "csrftoken":
-			c.csrfToken = cookie.Value
+		// Session and CSRF cookies are added, and updated from the
+		// response, by the cookie jar
+		resp, err := c.httpClient.Do(req)
+		if delay, ok := policy.retryDelay(ctx, attempt, resp, err); ok {
+			discardResponse(resp)
+			if err := sleep(ctx, delay); err != nil {
+				return nil, fmt.Errorf("request failed: %w", err)
+			}
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("request failed: %w", err)
 		}
-	}
 
-	return resp, nil
+		return resp, nil
+	}
 }
 
 // decodeResponse decodes a JSON response into the given target
@@ -137,8 +209,7 @@ func decodeResponse(resp *http.Response, target any) error {
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error (status %d): %s", resp.StatusCode, string(body))
+		return newAPIError(resp)
 	}
 
 	if target != nil {
@@ -150,35 +221,32 @@ func decodeResponse(resp *http.Response, target any) error {
 	return nil
 }
 
-// initSession visits the login page to initialize cookies (csrftoken)
-func (c *Client) initSession() error {
-	req, err := http.NewRequest(http.MethodGet, WebBaseURL+"/se/user/login/", nil)
+// initSession visits the login page to initialize cookies (csrftoken). The
+// jar keeps them for the login request.
+func (c *Client) initSession(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.webBaseURL+"/se/user/login/", nil)
 	if err != nil {
 		return fmt.Errorf("failed to create init request: %w", err)
 	}
 
-	c.setBrowserHeaders(req, WebBaseURL+"/se/")
+	c.setBrowserHeaders(req, c.webBaseURL+"/se/")
 
+	if err := c.limiter.wait(ctx); err != nil {
+		return fmt.Errorf("failed to init session: %w", err)
+	}
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
 		return fmt.Errorf("failed to init session: %w", err)
 	}
-	defer resp.Body.Close()
-
-	// Extract cookies
-	for _, cookie := range resp.Cookies() {
-		if cookie.Name == "csrftoken" {
-			c.csrfToken = cookie.Value
-		}
-	}
+	discardResponse(resp)
 
 	return nil
 }
 
 // Login authenticates with email and password
-func (c *Client) Login(email, password string) error {
+func (c *Client) Login(ctx context.Context, email, password string) error {
 	// First, visit the login page to get CSRF token and initial cookies
-	if err := c.initSession(); err != nil {
+	if err := c.initSession(ctx); err != nil {
 		return fmt.Errorf("failed to initialize session: %w", err)
 	}
 
@@ -187,19 +255,18 @@ func (c *Client) Login(email, password string) error {
 		"password": password,
 	}
 
-	resp, err := c.doRequest(http.MethodPost, "/user/login/", payload, WebBaseURL+"/se/user/login/")
+	resp, err := c.doRequest(ctx, http.MethodPost, "/user/login/", payload, c.webBaseURL+"/se/user/login/")
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("login failed (status %d): %s", resp.StatusCode, string(body))
+		return newAPIError(resp)
 	}
 
-	// Session ID is extracted in doRequest from cookies
-	if c.sessionID == "" {
+	// The session cookie is stored in the jar from the response
+	if c.SessionID() == "" {
 		return fmt.Errorf("login succeeded but no session cookie received")
 	}
 
@@ -207,11 +274,19 @@ func (c *Client) Login(email, password string) error {
 }
 
 // Search searches for products
-func (c *Client) Search(query string, page int) (*SearchResponse, error) {
+func (c *Client) Search(ctx context.Context, query string, page int, opts ...SearchOption) (*SearchResponse, error) {
+	var params searchParams
+	for _, opt := range opts {
+		opt(&params)
+	}
+
 	endpoint := fmt.Sprintf("/search/mixed/?q=%s&type=product&page=%d",
 		url.QueryEscape(query), page)
+	if params.perPage > 0 {
+		endpoint += fmt.Sprintf("&items=%d", params.perPage)
+	}
 
-	resp, err := c.doRequest(http.MethodGet, endpoint, nil, WebBaseURL+"/se/")
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/")
 	if err != nil {
 		return nil, err
 	}
@@ -224,9 +299,70 @@ func (c *Client) Search(query string, page int) (*SearchResponse, error) {
 	return &result, nil
 }
 
-// GetCart retrieves the current cart
-func (c *Client) GetCart() (*Cart, error) {
-	resp, err := c.doRequest(http.MethodGet, "/cart/?group_by=recipes", nil, WebBaseURL+"/se/")
+// GetProduct retrieves the full details of a product. The endpoint and the
+// shape of ProductDetail are unverified; see docs/API.md.
+func (c *Client) GetProduct(ctx context.Context, id int) (*ProductDetail, error) {
+	endpoint := fmt.Sprintf("/products/%d/", id)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var product ProductDetail
+	if err := decodeResponse(resp, &product); err != nil {
+		return nil, err
+	}
+
+	return &product, nil
+}
+
+// Suggest returns search terms completing a prefix, or correcting a
+// misspelled query
+func (c *Client) Suggest(ctx context.Context, prefix string) ([]Suggestion, error) {
+	endpoint := fmt.Sprintf("/search/mixed/?q=%s&type=suggestion", url.QueryEscape(prefix))
+
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var result SuggestionResponse
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Items, nil
+}
+
+// GetPromotedProducts lists the products currently on offer
+func (c *Client) GetPromotedProducts(ctx context.Context) ([]Product, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/campaigns/promoted_products/", nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var result PromotedProducts
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Items, nil
+}
+
+// GetCart retrieves the current cart, with items grouped by recipe
+func (c *Client) GetCart(ctx context.Context) (*Cart, error) {
+	return c.GetCartGrouped(ctx, GroupByRecipes)
+}
+
+// GetCartGrouped retrieves the current cart with items grouped as asked
+func (c *Client) GetCartGrouped(ctx context.Context, groupBy CartGrouping) (*Cart, error) {
+	endpoint := "/cart/"
+	if groupBy != GroupByNone {
+		endpoint += "?group_by=" + url.QueryEscape(string(groupBy))
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/")
 	if err != nil {
 		return nil, err
 	}
@@ -240,12 +376,12 @@ func (c *Client) GetCart() (*Cart, error) {
 }
 
 // AddToCart adds items to the cart
-func (c *Client) AddToCart(items []CartItem) (*Cart, error) {
+func (c *Client) AddToCart(ctx context.Context, items []CartItem) (*Cart, error) {
 	payload := map[string][]CartItem{
 		"items": items,
 	}
 
-	resp, err := c.doRequest(http.MethodPost, "/cart/items/", payload, WebBaseURL+"/se/")
+	resp, err := c.doRequest(ctx, http.MethodPost, "/cart/items/", payload, c.webBaseURL+"/se/")
 	if err != nil {
 		return nil, err
 	}
@@ -259,8 +395,8 @@ func (c *Client) AddToCart(items []CartItem) (*Cart, error) {
 }
 
 // ClearCart removes all items from the cart
-func (c *Client) ClearCart() (*Cart, error) {
-	resp, err := c.doRequest(http.MethodPost, "/cart/clear/", nil, WebBaseURL+"/se/cart/")
+func (c *Client) ClearCart(ctx context.Context) (*Cart, error) {
+	resp, err := c.doRequest(ctx, http.MethodPost, "/cart/clear/", nil, c.webBaseURL+"/se/cart/")
 	if err != nil {
 		return nil, err
 	}
@@ -272,3 +408,150 @@ func (c *Client) ClearCart() (*Cart, error) {
 
 	return &cart, nil
 }
+
+// GetDeliverySlots lists the delivery windows for the coming days
+func (c *Client) GetDeliverySlots(ctx context.Context, days int) (*DeliverySlots, error) {
+	endpoint := fmt.Sprintf("/slot-picker/slots/?num-days=%d", days)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var slots DeliverySlots
+	if err := decodeResponse(resp, &slots); err != nil {
+		return nil, err
+	}
+
+	return &slots, nil
+}
+
+// validationFields are the keys of a cart validation response
+var validationFields = []string{"is_valid", "unavailable_products", "quantity_limits", "minimum_order", "errors"}
+
+// ValidateCart checks whether the cart can be ordered as it is. The
+// response shape is unverified, so a response with none of the expected
+// fields is an error rather than a cart that is invalid for no reason.
+func (c *Client) ValidateCart(ctx context.Context) (*CartValidation, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/cart/validate/", nil, c.webBaseURL+"/se/cart/")
+	if err != nil {
+		return nil, err
+	}
+
+	var fields map[string]json.RawMessage
+	if err := decodeResponse(resp, &fields); err != nil {
+		return nil, err
+	}
+	if !slices.ContainsFunc(validationFields, func(key string) bool {
+		_, ok := fields[key]
+		return ok
+	}) {
+		return nil, fmt.Errorf("unexpected cart validation response: none of %s present", strings.Join(validationFields, ", "))
+	}
+
+	// Decode again from the fields, which were checked to be JSON
+	data, _ := json.Marshal(fields)
+	var validation CartValidation
+	if err := json.Unmarshal(data, &validation); err != nil {
+		return nil, fmt.Errorf("failed to decode response: %w", err)
+	}
+
+	return &validation, nil
+}
+
+// ReserveSlot holds a delivery slot for the user. The reservation replaces
+// any earlier one and lapses at ExpiresAt unless an order is placed.
+//
+// The reservation endpoints are unverified; see docs/API.md.
+func (c *Client) ReserveSlot(ctx context.Context, slotID string) (*SlotReservation, error) {
+	payload := map[string]string{
+		"slot_id": slotID,
+	}
+
+	resp, err := c.doRequest(ctx, http.MethodPost, "/slot-picker/reservations/", payload, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation SlotReservation
+	if err := decodeResponse(resp, &reservation); err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// GetSlotReservation returns the reserved delivery slot. It returns
+// ErrNotFound when no slot is reserved, which can't be told apart from the
+// unverified endpoint not existing.
+func (c *Client) GetSlotReservation(ctx context.Context) (*SlotReservation, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/slot-picker/reservations/current/", nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var reservation SlotReservation
+	if err := decodeResponse(resp, &reservation); err != nil {
+		return nil, err
+	}
+
+	return &reservation, nil
+}
+
+// ReleaseSlot gives up the reserved delivery slot
+func (c *Client) ReleaseSlot(ctx context.Context) error {
+	resp, err := c.doRequest(ctx, http.MethodDelete, "/slot-picker/reservations/current/", nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return err
+	}
+
+	return decodeResponse(resp, nil)
+}
+
+// GetPerks lists the member's active and available perks
+func (c *Client) GetPerks(ctx context.Context) ([]Perk, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/perks/", nil, c.webBaseURL+"/se/")
+	if err != nil {
+		return nil, err
+	}
+
+	var result Perks
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Items, nil
+}
+
+// GetOrders lists past and upcoming orders, newest first. The order
+// endpoints are unverified; see docs/API.md.
+func (c *Client) GetOrders(ctx context.Context) ([]Order, error) {
+	resp, err := c.doRequest(ctx, http.MethodGet, "/orders/", nil, c.webBaseURL+"/se/account/orders/")
+	if err != nil {
+		return nil, err
+	}
+
+	var result Orders
+	if err := decodeResponse(resp, &result); err != nil {
+		return nil, err
+	}
+
+	return result.Items, nil
+}
+
+// GetOrder retrieves an order with its lines
+func (c *Client) GetOrder(ctx context.Context, id int) (*Order, error) {
+	endpoint := fmt.Sprintf("/orders/%d/", id)
+
+	resp, err := c.doRequest(ctx, http.MethodGet, endpoint, nil, c.webBaseURL+"/se/account/orders/")
+	if err != nil {
+		return nil, err
+	}
+
+	var order Order
+	if err := decodeResponse(resp, &order); err != nil {
+		return nil, err
+	}
+
+	return &order, nil
+}
@@ -0,0 +1,379 @@
+package api_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+	"github.com/thepsadmin/mathemcli/internal/mathemtest"
+)
+
+// noDelay retries without waiting so tests stay fast
+var noDelay = api.RetryPolicy{MaxRetries: 2}
+
+// roundTripperFunc adapts a function to http.RoundTripper
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func newTestClient(t *testing.T, srv *mathemtest.Server, opts ...api.Option) *api.Client {
+	t.Helper()
+	opts = append(srv.ClientOptions(), opts...)
+	return api.NewClientWithSession(srv.NewSession(), "", opts...)
+}
+
+func TestLogin(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+
+	c := api.NewClient(srv.ClientOptions()...)
+	if err := c.Login(context.Background(), mathemtest.Email, mathemtest.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if c.SessionID() == "" || c.CSRFToken() == "" {
+		t.Errorf("missing session cookies: session=%q csrf=%q", c.SessionID(), c.CSRFToken())
+	}
+
+	if _, err := c.GetCart(context.Background()); err != nil {
+		t.Errorf("GetCart after login: %v", err)
+	}
+}
+
+func TestAPIErrorFieldErrors(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	_, err := c.AddToCart(context.Background(), []api.CartItem{{ProductID: 1, Quantity: 1}})
+
+	var apiErr *api.APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("expected *api.APIError, got %v", err)
+	}
+	if apiErr.StatusCode != 400 {
+		t.Errorf("StatusCode = %d, want 400", apiErr.StatusCode)
+	}
+	if got := apiErr.FieldCode("product_id"); got != "does_not_exist" {
+		t.Errorf("FieldCode(product_id) = %q, want does_not_exist", got)
+	}
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	tests := []struct {
+		status int
+		want   error
+	}{
+		{401, api.ErrSessionExpired},
+		{403, api.ErrSessionExpired},
+		{404, api.ErrNotFound},
+		{429, api.ErrRateLimited},
+		{500, api.ErrServer},
+	}
+
+	for _, tt := range tests {
+		srv := mathemtest.NewServer()
+		c := newTestClient(t, srv, api.WithRetryPolicy(api.NoRetries))
+		srv.Fail("/cart/", mathemtest.Failure{Status: tt.status})
+
+		_, err := c.GetCart(context.Background())
+		if !errors.Is(err, tt.want) {
+			t.Errorf("status %d: errors.Is(%v, %v) = false", tt.status, err, tt.want)
+		}
+		srv.Close()
+	}
+}
+
+func TestAPIErrorHidesHTML(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv, api.WithRetryPolicy(api.NoRetries))
+	srv.Fail("/cart/", mathemtest.Failure{Status: 403, Body: "<html><body>Access denied</body></html>"})
+
+	_, err := c.GetCart(context.Background())
+	if err == nil {
+		t.Fatal("expected error")
+	}
+	if strings.Contains(err.Error(), "<html>") {
+		t.Errorf("error should not include the HTML body: %v", err)
+	}
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv, api.WithRetryPolicy(noDelay))
+	srv.Fail("/cart/", mathemtest.Failure{Status: 502, Times: 5})
+
+	if _, err := c.GetCart(context.Background()); !errors.Is(err, api.ErrServer) {
+		t.Fatalf("expected server error, got %v", err)
+	}
+	if got := srv.Requests("/cart/"); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+}
+
+func TestRetryAfterTooLong(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv, api.WithRetryPolicy(api.DefaultRetryPolicy))
+	srv.Fail("/cart/", mathemtest.Failure{Status: 429, RetryAfter: "3600"})
+
+	if _, err := c.GetCart(context.Background()); !errors.Is(err, api.ErrRateLimited) {
+		t.Fatalf("expected rate limit error, got %v", err)
+	}
+	if got := srv.Requests("/cart/"); got != 1 {
+		t.Errorf("requests = %d, want 1", got)
+	}
+}
+
+func TestContextCancel(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if _, err := c.Search(ctx, "kaffe", 1); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+}
+
+func TestSessionCookieSentOnce(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+
+	var duplicates atomic.Int32
+	c := api.NewClient(append(srv.ClientOptions(), api.WithTransport(roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		if len(req.CookiesNamed("sessionid")) > 1 || len(req.CookiesNamed("csrftoken")) > 1 {
+			duplicates.Add(1)
+		}
+		return http.DefaultTransport.RoundTrip(req)
+	})))...)
+
+	if err := c.Login(context.Background(), mathemtest.Email, mathemtest.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if _, err := c.GetCart(context.Background()); err != nil {
+		t.Fatalf("GetCart: %v", err)
+	}
+	if n := duplicates.Load(); n > 0 {
+		t.Errorf("%d requests carried duplicate session cookies", n)
+	}
+}
+
+func TestTransportWithHTTPClient(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+
+	var calls atomic.Int32
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		calls.Add(1)
+		return http.DefaultTransport.RoundTrip(req)
+	})
+	opts := append(srv.ClientOptions(), api.WithTransport(rt), api.WithHTTPClient(&http.Client{}))
+	c := api.NewClient(opts...)
+
+	if err := c.Login(context.Background(), mathemtest.Email, mathemtest.Password); err != nil {
+		t.Fatalf("Login: %v", err)
+	}
+	if calls.Load() == 0 {
+		t.Error("WithHTTPClient dropped the transport set by WithTransport")
+	}
+}
+
+func TestTimeoutWithHTTPClient(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+
+	// The transport outlasts the timeout unless the request is canceled
+	rt := roundTripperFunc(func(req *http.Request) (*http.Response, error) {
+		select {
+		case <-req.Context().Done():
+			return nil, req.Context().Err()
+		case <-time.After(5 * time.Second):
+			return http.DefaultTransport.RoundTrip(req)
+		}
+	})
+	opts := append(srv.ClientOptions(),
+		api.WithRetryPolicy(api.NoRetries),
+		api.WithTimeout(50*time.Millisecond),
+		api.WithHTTPClient(&http.Client{Transport: rt}),
+	)
+	c := api.NewClient(opts...)
+
+	start := time.Now()
+	if _, err := c.Search(context.Background(), "kaffe", 1); err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("request took %v, WithHTTPClient dropped the timeout", elapsed)
+	}
+}
+
+// TestConcurrentUse shares one client between goroutines; run with -race
+func TestConcurrentUse(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 30)
+	for range 10 {
+		wg.Go(func() {
+			if _, err := c.Search(ctx, "kaffe", 1); err != nil {
+				errs <- err
+			}
+		})
+		wg.Go(func() {
+			if _, err := c.AddToCart(ctx, []api.CartItem{{ProductID: 3681, Quantity: 1}}); err != nil {
+				errs <- err
+			}
+		})
+		wg.Go(func() {
+			if _, err := c.GetCart(ctx); err != nil {
+				errs <- err
+			}
+			if c.SessionID() == "" {
+				errs <- errors.New("session lost")
+			}
+		})
+	}
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		t.Error(err)
+	}
+	if got := srv.CartQuantity(3681); got != 10 {
+		t.Errorf("cart quantity = %d, want 10", got)
+	}
+}
+
+// TestRateLimit checks that requests beyond the burst wait for the limiter
+// and that waiting gives up with the context
+func TestRateLimit(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	limit := api.RateLimit{PerSecond: 20, Burst: 2}
+	c := newTestClient(t, srv, api.WithRateLimit(limit))
+	ctx := context.Background()
+
+	// Two requests go at once, the next four wait 50ms each. Allow some
+	// slack for timer jitter and for the tokens refilled while the
+	// goroutines start; the bucket itself only refills from the first call.
+	start := time.Now()
+	var wg sync.WaitGroup
+	for range 6 {
+		wg.Go(func() {
+			if _, err := c.Search(ctx, "kaffe", 1); err != nil {
+				t.Errorf("Search: %v", err)
+			}
+		})
+	}
+	wg.Wait()
+
+	if elapsed := time.Since(start); elapsed < 180*time.Millisecond {
+		t.Errorf("6 requests took %v, want at least 180ms", elapsed)
+	}
+	if got := srv.Requests("/search/mixed/"); got != 6 {
+		t.Errorf("requests = %d, want 6", got)
+	}
+
+	// Waiting for the limiter gives up with the context
+	c = newTestClient(t, srv, api.WithRateLimit(api.RateLimit{PerSecond: 1, Burst: 1}))
+	if _, err := c.Search(ctx, "kaffe", 1); err != nil {
+		t.Fatalf("Search: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	defer cancel()
+	if _, err := c.Search(ctx, "kaffe", 1); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected deadline exceeded, got %v", err)
+	}
+}
+
+// TestConcurrentLogin logs in while other requests are in flight
+func TestConcurrentLogin(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := api.NewClient(srv.ClientOptions()...)
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	for range 5 {
+		wg.Go(func() {
+			if err := c.Login(ctx, mathemtest.Email, mathemtest.Password); err != nil {
+				t.Errorf("Login: %v", err)
+			}
+		})
+		wg.Go(func() {
+			c.Search(ctx, "mjölk", 1)
+			c.SessionID()
+			c.CSRFToken()
+		})
+	}
+	wg.Wait()
+
+	if _, err := c.GetCart(ctx); err != nil {
+		t.Errorf("GetCart after login: %v", err)
+	}
+}
+
+func TestSearchAll(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv)
+
+	for i := range 12 {
+		srv.AddProducts(mathemtest.Product(9000+i, "Testvara", "Test", "", "1.00", "", ""))
+	}
+
+	var ids []int
+	for p, err := range c.SearchAll(context.Background(), "testvara", api.WithPerPage(5)) {
+		if err != nil {
+			t.Fatalf("SearchAll: %v", err)
+		}
+		ids = append(ids, p.ID)
+	}
+	if len(ids) != 12 || ids[0] != 9000 || ids[11] != 9011 {
+		t.Errorf("SearchAll returned %v", ids)
+	}
+	if got := srv.Requests("/search/mixed/"); got != 3 {
+		t.Errorf("requests = %d, want 3", got)
+	}
+
+	// Breaking out early stops fetching pages
+	for range c.SearchAll(context.Background(), "testvara", api.WithPerPage(5)) {
+		break
+	}
+	if got := srv.Requests("/search/mixed/"); got != 4 {
+		t.Errorf("requests = %d, want 4", got)
+	}
+}
+
+func TestSearchAllError(t *testing.T) {
+	srv := mathemtest.NewServer()
+	defer srv.Close()
+	c := newTestClient(t, srv, api.WithRetryPolicy(api.NoRetries))
+	srv.Fail("/search/mixed/", mathemtest.Failure{Status: 500})
+
+	var errs int
+	for _, err := range c.SearchAll(context.Background(), "kaffe") {
+		if !errors.Is(err, api.ErrServer) {
+			t.Fatalf("expected server error, got %v", err)
+		}
+		errs++
+	}
+	if errs != 1 {
+		t.Errorf("got %d errors, want 1", errs)
+	}
+}
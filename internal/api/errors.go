@@ -0,0 +1,100 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Sentinel errors for common API failures. Use errors.Is to check for them.
+var (
+	ErrSessionExpired = errors.New("session expired or not authorized")
+	ErrNotFound       = errors.New("not found")
+	ErrRateLimited    = errors.New("rate limited")
+	ErrServer         = errors.New("server error")
+)
+
+// maxErrorBody limits how much of an error response is read
+const maxErrorBody = 64 << 10
+
+// FieldError describes a problem with a single request field
+type FieldError struct {
+	Message string `json:"message"`
+	Code    string `json:"code"`
+}
+
+// APIError is returned for non-2xx responses from the Mathem API
+type APIError struct {
+	StatusCode  int
+	Messages    []string
+	FieldErrors map[string]FieldError
+}
+
+// errorEnvelope is the JSON error structure returned by the API
+type errorEnvelope struct {
+	Errors      []string              `json:"errors"`
+	FieldErrors map[string]FieldError `json:"field_errors"`
+}
+
+// newAPIError builds an APIError from a failed response. Bodies that are not
+// the documented JSON envelope (e.g. HTML bot-protection pages) are dropped.
+func newAPIError(resp *http.Response) *APIError {
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+
+	var envelope errorEnvelope
+	if err := json.Unmarshal(body, &envelope); err == nil {
+		apiErr.Messages = envelope.Errors
+		apiErr.FieldErrors = envelope.FieldErrors
+	}
+
+	return apiErr
+}
+
+// Error implements the error interface
+func (e *APIError) Error() string {
+	var parts []string
+	parts = append(parts, e.Messages...)
+	for _, field := range slices.Sorted(maps.Keys(e.FieldErrors)) {
+		fe := e.FieldErrors[field]
+		if fe.Code != "" {
+			parts = append(parts, fmt.Sprintf("%s: %s (%s)", field, fe.Message, fe.Code))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s: %s", field, fe.Message))
+		}
+	}
+
+	if len(parts) == 0 {
+		if text := http.StatusText(e.StatusCode); text != "" {
+			parts = append(parts, strings.ToLower(text))
+		}
+	}
+
+	return fmt.Sprintf("API error (status %d): %s", e.StatusCode, strings.Join(parts, "; "))
+}
+
+// Is reports whether the error matches one of the sentinel errors
+func (e *APIError) Is(target error) bool {
+	switch target {
+	case ErrSessionExpired:
+		return e.StatusCode == http.StatusUnauthorized || e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode >= 500
+	}
+	return false
+}
+
+// FieldCode returns the error code reported for a field, if any
+func (e *APIError) FieldCode(field string) string {
+	return e.FieldErrors[field].Code
+}
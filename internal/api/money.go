@@ -0,0 +1,182 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Money is an exact amount of Swedish kronor in öre. The API sends prices
+// as decimal strings such as "19.95"; they are kept as integers so totals
+// and comparisons never suffer rounding errors. The currency is not part of
+// the amount, since Mathem only sells in SEK; responses that state it keep
+// it in their own Currency field.
+type Money struct {
+	Ore int64
+}
+
+// Kronor returns an amount of whole kronor
+func Kronor(kr int64) Money {
+	return Money{Ore: kr * 100}
+}
+
+// ParseMoney parses a decimal amount such as "19.95", "19,95", "-4" or
+// "1 234,50". Amounts with more than two decimals, such as unit prices
+// like "13.300" or "13.335", are rounded to the nearest öre.
+func ParseMoney(s string) (Money, error) {
+	orig := s
+	s = strings.NewReplacer(" ", "", "\u00a0", "", "\u202f", "").Replace(strings.TrimSpace(s))
+	s = strings.TrimSuffix(strings.TrimSuffix(s, "kr"), "SEK")
+
+	negative := strings.HasPrefix(s, "-")
+	s = strings.TrimPrefix(s, "-")
+
+	whole, frac, _ := strings.Cut(strings.Replace(s, ",", ".", 1), ".")
+	if whole == "" && frac == "" {
+		return Money{}, fmt.Errorf("invalid amount %q", orig)
+	}
+
+	var kr, ore int64
+	var err error
+	if whole != "" {
+		if kr, err = strconv.ParseInt(whole, 10, 64); err != nil || kr < 0 {
+			return Money{}, fmt.Errorf("invalid amount %q", orig)
+		}
+	}
+	if frac != "" {
+		if ore, err = strconv.ParseInt((frac + "0")[:2], 10, 64); err != nil || ore < 0 {
+			return Money{}, fmt.Errorf("invalid amount %q", orig)
+		}
+		if rest := frac[min(len(frac), 2):]; rest != "" {
+			if _, err := strconv.ParseUint(rest, 10, 64); err != nil {
+				return Money{}, fmt.Errorf("invalid amount %q", orig)
+			}
+			if rest[0] >= '5' {
+				ore++
+			}
+		}
+	}
+
+	m := Money{Ore: kr*100 + ore}
+	if negative {
+		m.Ore = -m.Ore
+	}
+	return m, nil
+}
+
+// UnmarshalJSON accepts "19.95", 19.95, "" and null
+func (m *Money) UnmarshalJSON(data []byte) error {
+	data = bytes.TrimSpace(data)
+	if bytes.Equal(data, []byte("null")) {
+		*m = Money{}
+		return nil
+	}
+
+	s := string(data)
+	if len(data) > 0 && data[0] == '"' {
+		if err := json.Unmarshal(data, &s); err != nil {
+			return err
+		}
+		if strings.TrimSpace(s) == "" {
+			*m = Money{}
+			return nil
+		}
+	}
+
+	parsed, err := ParseMoney(s)
+	if err != nil {
+		return err
+	}
+	*m = parsed
+	return nil
+}
+
+// MarshalJSON encodes the amount the way the API does, as "19.95"
+func (m Money) MarshalJSON() ([]byte, error) {
+	return json.Marshal(m.Decimal())
+}
+
+// Add returns m + o
+func (m Money) Add(o Money) Money {
+	return Money{Ore: m.Ore + o.Ore}
+}
+
+// Sub returns m - o
+func (m Money) Sub(o Money) Money {
+	return Money{Ore: m.Ore - o.Ore}
+}
+
+// Mul returns m multiplied by a quantity
+func (m Money) Mul(n int) Money {
+	return Money{Ore: m.Ore * int64(n)}
+}
+
+// Div returns m divided by a quantity, such as a pack's weight, rounded to
+// the nearest öre. Unlike the other operations it is not exact.
+func (m Money) Div(q float64) Money {
+	return Money{Ore: int64(math.Round(float64(m.Ore) / q))}
+}
+
+// Cmp returns -1, 0 or +1 depending on whether m is less than, equal to or
+// greater than o
+func (m Money) Cmp(o Money) int {
+	switch {
+	case m.Ore < o.Ore:
+		return -1
+	case m.Ore > o.Ore:
+		return 1
+	}
+	return 0
+}
+
+// Less reports whether m is less than o
+func (m Money) Less(o Money) bool {
+	return m.Cmp(o) < 0
+}
+
+// IsZero reports whether the amount is zero, which is also the case for
+// prices the API left empty
+func (m Money) IsZero() bool {
+	return m.Ore == 0
+}
+
+// Decimal formats the amount as the API does, e.g. "19.95"
+func (m Money) Decimal() string {
+	sign := ""
+	ore := m.Ore
+	if ore < 0 {
+		sign = "-"
+		ore = -ore
+	}
+	return fmt.Sprintf("%s%d.%02d", sign, ore/100, ore%100)
+}
+
+// Amount formats the amount in Swedish style without a currency, e.g.
+// "1 234,50"
+func (m Money) Amount() string {
+	sign := ""
+	ore := m.Ore
+	if ore < 0 {
+		sign = "-"
+		ore = -ore
+	}
+
+	kr := strconv.FormatInt(ore/100, 10)
+	var grouped strings.Builder
+	for i, digit := range kr {
+		if i > 0 && (len(kr)-i)%3 == 0 {
+			grouped.WriteByte(' ')
+		}
+		grouped.WriteRune(digit)
+	}
+
+	return fmt.Sprintf("%s%s,%02d", sign, grouped.String(), ore%100)
+}
+
+// String formats the amount in Swedish style, e.g. "19,95 kr"
+func (m Money) String() string {
+	return m.Amount() + " kr"
+}
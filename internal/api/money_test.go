@@ -0,0 +1,99 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParseMoney(t *testing.T) {
+	tests := []struct {
+		in   string
+		want int64
+	}{
+		{"19.95", 1995},
+		{"19,95", 1995},
+		{"19.9", 1990},
+		{"19", 1900},
+		{"0.10", 10},
+		{".5", 50},
+		{"-3.50", -350},
+		{"1 234,50", 123450},
+		{"19,95 kr", 1995},
+		{"19.950", 1995},
+		{"13.300", 1330},
+		{"13.334", 1333},
+		{"13.335", 1334},
+		{"1.999", 200},
+		{"-3.505", -351},
+	}
+	for _, tt := range tests {
+		got, err := ParseMoney(tt.in)
+		if err != nil || got.Ore != tt.want {
+			t.Errorf("ParseMoney(%q) = %d, %v; want %d", tt.in, got.Ore, err, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "abc", "1.2.3", "--1", "1.99x"} {
+		if _, err := ParseMoney(bad); err == nil {
+			t.Errorf("ParseMoney(%q) should fail", bad)
+		}
+	}
+}
+
+func TestMoneyJSON(t *testing.T) {
+	var v struct {
+		A, B, C, D Money
+	}
+	if err := json.Unmarshal([]byte(`{"A":"19.950","B":19.95,"C":"","D":null}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if v.A.Ore != 1995 || v.B.Ore != 1995 || !v.C.IsZero() || !v.D.IsZero() {
+		t.Errorf("unexpected amounts: %+v", v)
+	}
+
+	data, err := json.Marshal(Money{Ore: 1995})
+	if err != nil || string(data) != `"19.95"` {
+		t.Errorf("Marshal = %s, %v", data, err)
+	}
+}
+
+func TestMoneyArithmetic(t *testing.T) {
+	// 0.1 + 0.2 style sums stay exact
+	sum := Money{}
+	for range 10 {
+		sum = sum.Add(Money{Ore: 10})
+	}
+	if sum != Kronor(1) {
+		t.Errorf("sum = %v, want 1,00 kr", sum)
+	}
+
+	if got := (Money{Ore: 1995}).Mul(3); got.Ore != 5985 {
+		t.Errorf("Mul = %d, want 5985", got.Ore)
+	}
+	if got := (Money{Ore: 5995}).Div(0.5); got.Ore != 11990 {
+		t.Errorf("Div = %d, want 11990", got.Ore)
+	}
+	if got := Kronor(10).Div(3); got.Ore != 333 {
+		t.Errorf("Div = %d, want 333", got.Ore)
+	}
+	if !(Money{Ore: 1995}).Less(Kronor(20)) || Kronor(20).Cmp(Money{Ore: 2000}) != 0 {
+		t.Error("comparison failed")
+	}
+}
+
+func TestMoneyString(t *testing.T) {
+	tests := []struct {
+		m    Money
+		want string
+	}{
+		{Money{Ore: 1995}, "19,95 kr"},
+		{Money{Ore: 5}, "0,05 kr"},
+		{Money{Ore: -350}, "-3,50 kr"},
+		{Money{Ore: 123456789}, "1 234 567,89 kr"},
+	}
+	for _, tt := range tests {
+		if got := tt.m.String(); got != tt.want {
+			t.Errorf("%d öre = %q, want %q", tt.m.Ore, got, tt.want)
+		}
+	}
+}
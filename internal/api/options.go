@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/cookiejar"
+	"strings"
+	"time"
+)
+
+// Option configures a Client
+type Option func(*Client)
+
+// WithBaseURL sets the API base URL, e.g. to point at a local stand-in server
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// WithWebBaseURL sets the website URL used for the login page and referers
+func WithWebBaseURL(webBaseURL string) Option {
+	return func(c *Client) {
+		c.webBaseURL = strings.TrimRight(webBaseURL, "/")
+	}
+}
+
+// WithHTTPClient uses a copy of the given HTTP client. A cookie jar is added
+// if the client does not have one, since sessions depend on it. A jar of
+// your own must be safe for concurrent use, like net/http/cookiejar.
+func WithHTTPClient(httpClient *http.Client) Option {
+	return func(c *Client) {
+		hc := *httpClient
+		if hc.Jar == nil {
+			hc.Jar, _ = cookiejar.New(nil)
+		}
+		c.httpClient = &hc
+	}
+}
+
+// WithTransport sets the HTTP transport, e.g. to wrap it for logging or
+// metrics. It takes precedence over the transport of a client passed to
+// WithHTTPClient, before or after it.
+func WithTransport(transport http.RoundTripper) Option {
+	return func(c *Client) {
+		c.transport = transport
+	}
+}
+
+// WithUserAgent overrides the browser-like User-Agent header
+func WithUserAgent(userAgent string) Option {
+	return func(c *Client) {
+		c.userAgent = userAgent
+	}
+}
+
+// WithTimeout sets the timeout for each individual HTTP request. Like
+// WithTransport, it takes precedence over a client passed to WithHTTPClient.
+func WithTimeout(timeout time.Duration) Option {
+	return func(c *Client) {
+		c.timeout = &timeout
+	}
+}
+
+// WithRetryPolicy changes how safe requests are retried
+func WithRetryPolicy(policy RetryPolicy) Option {
+	return func(c *Client) {
+		c.retry = policy
+	}
+}
+
+// WithRateLimit changes how fast requests may be sent; NoRateLimit
+// disables the limit
+func WithRateLimit(limit RateLimit) Option {
+	return func(c *Client) {
+		c.rateLimit = limit
+	}
+}
@@ -0,0 +1,67 @@
+package api
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RateLimit caps how fast a client sends requests, so that concurrent
+// callers don't flood the API. Requests beyond the burst wait their turn.
+type RateLimit struct {
+	// PerSecond is the sustained number of requests per second; zero or
+	// less means no limit
+	PerSecond float64
+	// Burst is how many requests may be sent at once before the limit
+	// applies
+	Burst int
+}
+
+// DefaultRateLimit is used by new clients
+var DefaultRateLimit = RateLimit{
+	PerSecond: 5,
+	Burst:     10,
+}
+
+// NoRateLimit disables rate limiting
+var NoRateLimit = RateLimit{}
+
+// rateLimiter is a token bucket shared by every request of a client
+type rateLimiter struct {
+	mu     sync.Mutex
+	limit  RateLimit
+	tokens float64
+	last   time.Time
+}
+
+// newRateLimiter returns a limiter for the limit, or nil if it is disabled
+func newRateLimiter(limit RateLimit) *rateLimiter {
+	if limit.PerSecond <= 0 {
+		return nil
+	}
+	limit.Burst = max(limit.Burst, 1)
+	return &rateLimiter{limit: limit, tokens: float64(limit.Burst)}
+}
+
+// wait blocks until a request may be sent. Each call reserves its slot, so
+// concurrent callers are spread out instead of all waking at once.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(float64(l.limit.Burst), l.tokens+now.Sub(l.last).Seconds()*l.limit.PerSecond)
+	}
+	l.last = now
+	l.tokens--
+	delay := time.Duration(-l.tokens / l.limit.PerSecond * float64(time.Second))
+	l.mu.Unlock()
+
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	return sleep(ctx, delay)
+}
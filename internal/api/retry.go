@@ -0,0 +1,156 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"syscall"
+	"time"
+)
+
+// RetryPolicy controls how safe (GET) requests are retried on transient
+// failures. Requests that modify state, such as adding to the cart, are
+// never retried because replaying them is not harmless.
+type RetryPolicy struct {
+	// MaxRetries is the number of retries after the first attempt
+	MaxRetries int
+	// BaseDelay is the backoff before the first retry; it doubles each time
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff and the Retry-After delay we are willing to wait
+	MaxDelay time.Duration
+}
+
+// DefaultRetryPolicy is used by new clients
+var DefaultRetryPolicy = RetryPolicy{
+	MaxRetries: 2,
+	BaseDelay:  500 * time.Millisecond,
+	MaxDelay:   10 * time.Second,
+}
+
+// NoRetries disables retries entirely
+var NoRetries = RetryPolicy{}
+
+// isSafeMethod reports whether a request can be replayed without side effects
+func isSafeMethod(method string) bool {
+	return method == http.MethodGet || method == http.MethodHead
+}
+
+// isRetryableStatus reports whether a status code indicates a transient failure
+func isRetryableStatus(code int) bool {
+	return code == http.StatusTooManyRequests ||
+		(code >= 500 && code != http.StatusNotImplemented)
+}
+
+// isTransientError reports whether a transport error is likely to go away
+// on its own: a timeout, a refused or reset connection, or a connection
+// closed before the response was complete. Errors such as a malformed URL,
+// a TLS failure or a request missing from a cassette are not.
+func isTransientError(err error) bool {
+	// A context deadline is also a net.Error timeout, but retrying it
+	// can't succeed
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return true
+	}
+
+	return errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, io.EOF)
+}
+
+// retryDelay decides whether a failed attempt should be retried and how long
+// to wait first. attempt is zero for the first retry.
+func (p RetryPolicy) retryDelay(ctx context.Context, attempt int, resp *http.Response, err error) (time.Duration, bool) {
+	if attempt >= p.MaxRetries || ctx.Err() != nil {
+		return 0, false
+	}
+
+	if err != nil {
+		if !isTransientError(err) {
+			return 0, false
+		}
+		return p.backoff(attempt), true
+	}
+
+	if !isRetryableStatus(resp.StatusCode) {
+		return 0, false
+	}
+
+	if delay, ok := parseRetryAfter(resp.Header.Get("Retry-After")); ok {
+		// Don't sit around for minutes; report the error instead
+		if p.MaxDelay > 0 && delay > p.MaxDelay {
+			return 0, false
+		}
+		return delay, true
+	}
+
+	return p.backoff(attempt), true
+}
+
+// backoff returns an exponential delay with jitter for the given attempt
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	delay := p.BaseDelay << attempt
+	if delay <= 0 || (p.MaxDelay > 0 && delay > p.MaxDelay) {
+		delay = p.MaxDelay
+	}
+	if delay <= 0 {
+		return 0
+	}
+
+	// Wait somewhere between half and the full delay so parallel
+	// clients don't retry in lockstep
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+// parseRetryAfter parses a Retry-After header in seconds or HTTP-date form
+func parseRetryAfter(value string) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+
+	if t, err := http.ParseTime(value); err == nil {
+		return max(time.Until(t), 0), true
+	}
+
+	return 0, false
+}
+
+// discardResponse drains and closes a response that will not be used, so
+// the underlying connection can be reused
+func discardResponse(resp *http.Response) {
+	if resp == nil {
+		return
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, maxErrorBody))
+	resp.Body.Close()
+}
+
+// sleep waits for the given duration or until the context is done
+func sleep(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
@@ -0,0 +1,43 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+)
+
+func TestRetryDelayTransportErrors(t *testing.T) {
+	urlErr := func(err error) error {
+		return &url.Error{Op: "Get", URL: "https://api.mathem.io/search/mixed/", Err: err}
+	}
+
+	tests := []struct {
+		name string
+		err  error
+		want bool
+	}{
+		{"timeout", urlErr(os.ErrDeadlineExceeded), true},
+		{"connection reset", urlErr(&net.OpError{Op: "read", Err: os.NewSyscallError("read", syscall.ECONNRESET)}), true},
+		{"connection refused", urlErr(&net.OpError{Op: "dial", Err: os.NewSyscallError("connect", syscall.ECONNREFUSED)}), true},
+		{"truncated response", urlErr(io.ErrUnexpectedEOF), true},
+		{"closed connection", urlErr(io.EOF), true},
+		{"context deadline", urlErr(context.DeadlineExceeded), false},
+		{"canceled", urlErr(context.Canceled), false},
+		{"unknown host", urlErr(&net.DNSError{Err: "no such host", Name: "api.mathem.io", IsNotFound: true}), false},
+		{"cassette miss", fmt.Errorf("no recorded interaction for GET /search/mixed/"), false},
+		{"other", urlErr(errors.New("unsupported protocol scheme")), false},
+	}
+
+	policy := RetryPolicy{MaxRetries: 2}
+	for _, tt := range tests {
+		if _, got := policy.retryDelay(context.Background(), 0, nil, tt.err); got != tt.want {
+			t.Errorf("%s: retry = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package api
+
+import (
+	"context"
+	"iter"
+)
+
+// SearchOption configures a search request
+type SearchOption func(*searchParams)
+
+// searchParams holds the optional search parameters
+type searchParams struct {
+	perPage int
+}
+
+// WithPerPage sets the number of results per page; the API's default is
+// used otherwise
+func WithPerPage(n int) SearchOption {
+	return func(p *searchParams) {
+		p.perPage = n
+	}
+}
+
+// SearchAll returns an iterator over every product matching the query,
+// fetching pages as needed. Stop iterating to stop fetching. A failed page
+// is yielded as an error, after which the iteration ends.
+func (c *Client) SearchAll(ctx context.Context, query string, opts ...SearchOption) iter.Seq2[Product, error] {
+	return func(yield func(Product, error) bool) {
+		for page := 1; ; page++ {
+			result, err := c.Search(ctx, query, page, opts...)
+			if err != nil {
+				yield(Product{}, err)
+				return
+			}
+
+			for _, item := range result.Items {
+				if item.Type != "product" {
+					continue
+				}
+				if !yield(item, nil) {
+					return
+				}
+			}
+
+			// An empty page would otherwise loop forever on a confused server
+			if !result.Attributes.HasMoreItems || len(result.Items) == 0 {
+				return
+			}
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package api
 
+import (
+	"encoding/json"
+	"slices"
+	"time"
+)
+
 // SearchResponse represents the search API response
 type SearchResponse struct {
 	Type       string           `json:"type"`
@@ -14,6 +20,23 @@ type SearchAttributes struct {
 	HasMoreItems bool `json:"has_more_items"`
 }
 
+// SuggestionResponse represents the autocomplete API response
+type SuggestionResponse struct {
+	Type  string       `json:"type"`
+	Items []Suggestion `json:"items"`
+}
+
+// Suggestion is a search term suggested for a prefix or misspelling
+type Suggestion struct {
+	Type       string               `json:"type"`
+	Attributes SuggestionAttributes `json:"attributes"`
+}
+
+// SuggestionAttributes contains the suggested search term
+type SuggestionAttributes struct {
+	Text string `json:"text"`
+}
+
 // Product represents a product in search results
 type Product struct {
 	ID         int               `json:"id"`
@@ -23,16 +46,148 @@ type Product struct {
 
 // ProductAttributes contains product details
 type ProductAttributes struct {
-	Name                      string         `json:"name"`
-	FullName                  string         `json:"full_name"`
-	Brand                     string         `json:"brand"`
-	NameExtra                 string         `json:"name_extra"`
-	GrossPrice                string         `json:"gross_price"`
-	GrossUnitPrice            string         `json:"gross_unit_price"`
-	UnitPriceQuantityAbbr     string         `json:"unit_price_quantity_abbreviation"`
-	Currency                  string         `json:"currency"`
-	Availability              Availability   `json:"availability"`
-	Images                    []ProductImage `json:"images"`
+	Name                  string         `json:"name"`
+	FullName              string         `json:"full_name"`
+	Brand                 string         `json:"brand"`
+	NameExtra             string         `json:"name_extra"`
+	GrossPrice            Money          `json:"gross_price"`
+	GrossUnitPrice        Money          `json:"gross_unit_price"`
+	UnitPriceQuantityAbbr string         `json:"unit_price_quantity_abbreviation"`
+	Currency              string         `json:"currency"`
+	Availability          Availability   `json:"availability"`
+	Images                []ProductImage `json:"images"`
+	Campaign              *Campaign      `json:"campaign,omitempty"`
+}
+
+// Campaign is an offer on a product, either a reduced price or a
+// multi-buy such as "2 för 35 kr"
+type Campaign struct {
+	Name          string    `json:"name"`
+	DiscountLabel string    `json:"discount_label"`
+	OriginalPrice Money     `json:"original_price"`
+	MultiBuy      *MultiBuy `json:"multi_buy,omitempty"`
+	ValidFrom     time.Time `json:"valid_from"`
+	ValidUntil    time.Time `json:"valid_until"`
+}
+
+// UnmarshalJSON decodes a campaign leniently, since its shape is assumed
+// rather than captured: a field that doesn't decode, such as an empty
+// valid_until, is left zero instead of failing the whole search response.
+// A string is taken as the campaign's name and any other non-object as an
+// empty campaign.
+func (c *Campaign) UnmarshalJSON(data []byte) error {
+	*c = Campaign{}
+
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		_ = json.Unmarshal(data, &c.Name)
+		return nil
+	}
+
+	decode := func(key string, v any) {
+		if raw, ok := fields[key]; ok {
+			_ = json.Unmarshal(raw, v)
+		}
+	}
+	decode("name", &c.Name)
+	decode("discount_label", &c.DiscountLabel)
+	decode("original_price", &c.OriginalPrice)
+	decode("multi_buy", &c.MultiBuy)
+	c.ValidFrom = parseCampaignTime(fields["valid_from"])
+	c.ValidUntil = parseCampaignTime(fields["valid_until"])
+	return nil
+}
+
+// parseCampaignTime accepts RFC 3339 timestamps and plain dates, and
+// returns the zero time for anything else
+func parseCampaignTime(raw json.RawMessage) time.Time {
+	var s string
+	if err := json.Unmarshal(raw, &s); err != nil || s == "" {
+		return time.Time{}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t
+	}
+	if t, err := time.ParseInLocation(time.DateOnly, s, time.Local); err == nil {
+		return t
+	}
+	return time.Time{}
+}
+
+// IsZero reports whether the campaign carries no offer at all
+func (c *Campaign) IsZero() bool {
+	return c == nil || (c.Name == "" && c.DiscountLabel == "" &&
+		c.OriginalPrice.IsZero() && c.MultiBuy == nil)
+}
+
+// MultiBuy is a price for buying several of a product
+type MultiBuy struct {
+	Quantity int   `json:"quantity"`
+	Price    Money `json:"price"`
+}
+
+// OnOffer reports whether the product has a campaign worth showing
+func (a ProductAttributes) OnOffer() bool {
+	return !a.Campaign.IsZero()
+}
+
+// DiscountPercent returns how much cheaper the campaign makes the product,
+// in whole percent, or 0 without a campaign
+func (a ProductAttributes) DiscountPercent() int {
+	c := a.Campaign
+	if c == nil {
+		return 0
+	}
+
+	// Compare what the units cost without the offer with what they cost
+	// with it
+	regular, offer := c.OriginalPrice, a.GrossPrice
+	if c.MultiBuy != nil && c.MultiBuy.Quantity > 0 {
+		unit := a.GrossPrice
+		if !c.OriginalPrice.IsZero() {
+			unit = c.OriginalPrice
+		}
+		regular, offer = unit.Mul(c.MultiBuy.Quantity), c.MultiBuy.Price
+	}
+
+	if regular.Ore <= 0 || !offer.Less(regular) {
+		return 0
+	}
+	return int((regular.Ore - offer.Ore) * 100 / regular.Ore)
+}
+
+// PromotedProducts is the response of the promoted products endpoint
+type PromotedProducts struct {
+	Items []Product `json:"items"`
+}
+
+// ProductDetail is the full product information shown on a product page.
+// It has every field of ProductAttributes plus the details search results
+// leave out. The shape is unverified; see docs/API.md.
+type ProductDetail struct {
+	ID int `json:"id"`
+	ProductAttributes
+	AbsoluteURL     string         `json:"absolute_url"`
+	Description     string         `json:"description"`
+	Ingredients     string         `json:"ingredients"`
+	Nutrition       NutritionFacts `json:"nutrition"`
+	Allergens       []string       `json:"allergens"`
+	CountryOfOrigin string         `json:"country_of_origin"`
+	Labels          []string       `json:"labels"`
+	EAN             string         `json:"ean"`
+	Storage         string         `json:"storage"`
+}
+
+// NutritionFacts is a product's nutrition table
+type NutritionFacts struct {
+	Basis  string           `json:"basis"`
+	Values []NutritionValue `json:"values"`
+}
+
+// NutritionValue is a row of the nutrition table, e.g. "Fett", "1,5 g"
+type NutritionValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
 }
 
 // Availability indicates if a product is available
@@ -62,16 +217,51 @@ type Cart struct {
 	ActiveGrouping       string         `json:"active_grouping"`
 	LabelText            string         `json:"label_text"`
 	ProductQuantityCount int            `json:"product_quantity_count"`
-	DisplayPrice         string         `json:"display_price"`
-	TotalGrossAmount     string         `json:"total_gross_amount"`
+	DisplayPrice         Money          `json:"display_price"`
+	TotalGrossAmount     Money          `json:"total_gross_amount"`
 	Currency             string         `json:"currency"`
 	Groups               []CartGroup    `json:"groups"`
 	SummaryLines         []SummaryGroup `json:"summary_lines"`
 }
 
-// CartGroup represents a group of items in the cart
+// Item returns the cart line for a product, if it is in the cart
+func (c *Cart) Item(productID int) (CartGroupItem, bool) {
+	for _, group := range c.Groups {
+		for _, item := range group.Items {
+			if item.Product.ID == productID {
+				return item, true
+			}
+		}
+	}
+	return CartGroupItem{}, false
+}
+
+// Quantity returns how many of a product are in the cart
+func (c *Cart) Quantity(productID int) int {
+	item, _ := c.Item(productID)
+	return item.Quantity
+}
+
+// CartGrouping selects how the items of a cart are grouped
+type CartGrouping string
+
+// Cart groupings supported by the API
+const (
+	GroupByRecipes    CartGrouping = "recipes"
+	GroupByCategories CartGrouping = "categories"
+	// GroupByNone leaves out group_by, putting every item in one group
+	GroupByNone CartGrouping = ""
+)
+
+// CartGroup represents a group of items in the cart, such as a store
+// department or a recipe. Items outside any recipe are in a group without
+// a name.
 type CartGroup struct {
-	Items []CartGroupItem `json:"items"`
+	ID       string          `json:"id"`
+	Name     string          `json:"name"`
+	Type     string          `json:"type"`
+	Subtotal Money           `json:"subtotal"`
+	Items    []CartGroupItem `json:"items"`
 }
 
 // CartGroupItem represents an item in the cart
@@ -79,19 +269,19 @@ type CartGroupItem struct {
 	Product      CartProduct `json:"product"`
 	ItemID       int         `json:"item_id"`
 	Quantity     int         `json:"quantity"`
-	DisplayPrice string      `json:"display_price_total"`
+	DisplayPrice Money       `json:"display_price_total"`
 }
 
 // CartProduct contains product info within the cart
 type CartProduct struct {
-	ID            int    `json:"id"`
-	FullName      string `json:"full_name"`
-	Brand         string `json:"brand"`
-	Name          string `json:"name"`
-	NameExtra     string `json:"name_extra"`
-	GrossPrice    string `json:"gross_price"`
-	Currency      string `json:"currency"`
-	AbsoluteURL   string `json:"absolute_url"`
+	ID          int    `json:"id"`
+	FullName    string `json:"full_name"`
+	Brand       string `json:"brand"`
+	Name        string `json:"name"`
+	NameExtra   string `json:"name_extra"`
+	GrossPrice  Money  `json:"gross_price"`
+	Currency    string `json:"currency"`
+	AbsoluteURL string `json:"absolute_url"`
 }
 
 // SummaryGroup represents a summary section
@@ -103,7 +293,7 @@ type SummaryGroup struct {
 // SummaryLine represents a line in the cart summary
 type SummaryLine struct {
 	Description string `json:"description"`
-	GrossAmount string `json:"gross_amount"`
+	GrossAmount Money  `json:"gross_amount"`
 	Name        string `json:"name"`
 }
 
@@ -112,3 +302,159 @@ type CartItem struct {
 	ProductID int `json:"product_id"`
 	Quantity  int `json:"quantity"`
 }
+
+// DeliverySlots is the slot picker response, one entry per delivery day
+type DeliverySlots struct {
+	Days []DeliveryDay `json:"days"`
+}
+
+// DeliveryDay lists the delivery windows offered on a date
+type DeliveryDay struct {
+	Date  string         `json:"date"`
+	Slots []DeliverySlot `json:"slots"`
+}
+
+// DeliverySlot is a delivery time window
+type DeliverySlot struct {
+	ID          string    `json:"id"`
+	StartTime   time.Time `json:"start_time"`
+	EndTime     time.Time `json:"end_time"`
+	DeliveryFee Money     `json:"delivery_fee"`
+	IsAvailable bool      `json:"is_available"`
+	IsEco       bool      `json:"is_eco"`
+}
+
+// SlotReservation is a delivery slot held for the user until it expires
+type SlotReservation struct {
+	Slot      DeliverySlot `json:"slot"`
+	ExpiresAt time.Time    `json:"expires_at"`
+}
+
+// CartValidation lists the problems that stop the cart from being ordered
+type CartValidation struct {
+	IsValid             bool                 `json:"is_valid"`
+	UnavailableProducts []UnavailableProduct `json:"unavailable_products"`
+	QuantityLimits      []QuantityLimit      `json:"quantity_limits"`
+	MinimumOrder        *MinimumOrder        `json:"minimum_order"`
+	Errors              []ValidationError    `json:"errors"`
+}
+
+// UnavailableProduct is a product in the cart that can't be delivered
+type UnavailableProduct struct {
+	ProductID int    `json:"product_id"`
+	FullName  string `json:"full_name"`
+	Reason    string `json:"reason"`
+}
+
+// QuantityLimit is a product in the cart exceeding its maximum quantity
+type QuantityLimit struct {
+	ProductID   int    `json:"product_id"`
+	FullName    string `json:"full_name"`
+	Quantity    int    `json:"quantity"`
+	MaxQuantity int    `json:"max_quantity"`
+}
+
+// MinimumOrder is reported when the cart total is below the minimum order
+type MinimumOrder struct {
+	MinimumAmount Money `json:"minimum_amount"`
+	CurrentAmount Money `json:"current_amount"`
+}
+
+// Shortfall returns how much more has to be added to the cart
+func (m MinimumOrder) Shortfall() Money {
+	return m.MinimumAmount.Sub(m.CurrentAmount)
+}
+
+// ValidationError is any other problem blocking the order, such as a
+// missing delivery slot
+type ValidationError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Perks is the response of the perks endpoint
+type Perks struct {
+	Items []Perk `json:"items"`
+}
+
+// Perk is a member reward. Available perks don't apply to an order until
+// they are active. The shape is unverified, see docs/API.md; a real response
+// may leave out MinimumAmount and ProductIDs in particular.
+type Perk struct {
+	ID            int       `json:"id"`
+	Title         string    `json:"title"`
+	Description   string    `json:"description"`
+	Conditions    string    `json:"conditions"`
+	MinimumAmount Money     `json:"minimum_amount"`
+	ProductIDs    []int     `json:"product_ids"`
+	IsActive      bool      `json:"is_active"`
+	ExpiresAt     time.Time `json:"expires_at"`
+}
+
+// PerkProgress describes how far a cart is from qualifying for a perk
+type PerkProgress struct {
+	Qualifies bool
+	// Missing is how much more the cart needs to reach MinimumAmount
+	Missing Money
+	// MissingProduct is set when none of the perk's products is in the cart
+	MissingProduct bool
+}
+
+// HasConditions reports whether the perk has a minimum amount or products
+// to check a cart against. Without them Progress would report any cart as
+// qualifying.
+func (p Perk) HasConditions() bool {
+	return !p.MinimumAmount.IsZero() || len(p.ProductIDs) > 0
+}
+
+// Progress checks the perk's conditions against a cart
+func (p Perk) Progress(cart *Cart) PerkProgress {
+	var progress PerkProgress
+
+	if cart.DisplayPrice.Less(p.MinimumAmount) {
+		progress.Missing = p.MinimumAmount.Sub(cart.DisplayPrice)
+	}
+
+	if len(p.ProductIDs) > 0 {
+		progress.MissingProduct = true
+		for _, group := range cart.Groups {
+			for _, item := range group.Items {
+				if slices.Contains(p.ProductIDs, item.Product.ID) {
+					progress.MissingProduct = false
+				}
+			}
+		}
+	}
+
+	progress.Qualifies = progress.Missing.IsZero() && !progress.MissingProduct
+	return progress
+}
+
+// Orders is the response of the order list endpoint
+type Orders struct {
+	Items []Order `json:"items"`
+}
+
+// Order is a placed order. Lines are only included when fetching a single
+// order.
+type Order struct {
+	ID                   int         `json:"id"`
+	OrderNumber          string      `json:"order_number"`
+	Status               string      `json:"status"`
+	IsUpcoming           bool        `json:"is_upcoming"`
+	CreatedAt            time.Time   `json:"created_at"`
+	DeliveryStart        time.Time   `json:"delivery_start"`
+	DeliveryEnd          time.Time   `json:"delivery_end"`
+	ProductQuantityCount int         `json:"product_quantity_count"`
+	TotalGrossAmount     Money       `json:"total_gross_amount"`
+	Lines                []OrderLine `json:"lines,omitempty"`
+}
+
+// OrderLine is a product in an order. Availability is the product's
+// current availability, for reordering.
+type OrderLine struct {
+	Product      CartProduct  `json:"product"`
+	Quantity     int          `json:"quantity"`
+	DisplayPrice Money        `json:"display_price_total"`
+	Availability Availability `json:"availability"`
+}
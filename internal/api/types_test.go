@@ -0,0 +1,92 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestDiscountPercent(t *testing.T) {
+	tests := []struct {
+		name  string
+		price Money
+		c     *Campaign
+		want  int
+	}{
+		{"no campaign", Kronor(20), nil, 0},
+		{"reduced price", Money{Ore: 11120}, &Campaign{OriginalPrice: Kronor(139)}, 20},
+		{"multi-buy", Money{Ore: 6495}, &Campaign{MultiBuy: &MultiBuy{Quantity: 2, Price: Kronor(110)}}, 15},
+		{"multi-buy on reduced price", Kronor(18), &Campaign{OriginalPrice: Kronor(20), MultiBuy: &MultiBuy{Quantity: 2, Price: Kronor(30)}}, 25},
+		{"no saving", Kronor(20), &Campaign{MultiBuy: &MultiBuy{Quantity: 2, Price: Kronor(40)}}, 0},
+		{"label only", Kronor(20), &Campaign{DiscountLabel: "Nyhet"}, 0},
+	}
+
+	for _, tt := range tests {
+		attr := ProductAttributes{GrossPrice: tt.price, Campaign: tt.c}
+		if got := attr.DiscountPercent(); got != tt.want {
+			t.Errorf("%s: DiscountPercent() = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPerkHasConditions(t *testing.T) {
+	tests := []struct {
+		name string
+		perk Perk
+		want bool
+	}{
+		{"minimum amount", Perk{MinimumAmount: Kronor(800)}, true},
+		{"products", Perk{ProductIDs: []int{4410}}, true},
+		{"text only", Perk{Conditions: "Handla för minst 800 kr"}, false},
+	}
+
+	for _, tt := range tests {
+		if got := tt.perk.HasConditions(); got != tt.want {
+			t.Errorf("%s: HasConditions() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestCampaignUnmarshalJSON(t *testing.T) {
+	data := []byte(`{"items": [
+		{"id": 1, "attributes": {"gross_price": "64.95", "campaign": {
+			"discount_label": "2 för 110 kr",
+			"multi_buy": {"quantity": 2, "price": "110.00"},
+			"valid_from": "",
+			"valid_until": "2026-10-24"
+		}}},
+		{"id": 2, "attributes": {"gross_price": "19.95", "campaign": "Veckans erbjudande"}},
+		{"id": 3, "attributes": {"gross_price": "19.95", "campaign": {"original_price": "gratis", "valid_until": 1761256800}}},
+		{"id": 4, "attributes": {"gross_price": "19.95", "campaign": []}},
+		{"id": 5, "attributes": {"gross_price": "19.95", "campaign": null}}
+	]}`)
+
+	var resp SearchResponse
+	if err := json.Unmarshal(data, &resp); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if len(resp.Items) != 5 {
+		t.Fatalf("got %d items, want 5", len(resp.Items))
+	}
+
+	c := resp.Items[0].Attributes.Campaign
+	if c.DiscountLabel != "2 för 110 kr" || c.MultiBuy == nil || c.MultiBuy.Price != Kronor(110) {
+		t.Errorf("campaign = %+v", c)
+	}
+	if !c.ValidFrom.IsZero() {
+		t.Errorf("ValidFrom = %v, want zero", c.ValidFrom)
+	}
+	if got := c.ValidUntil.Format(time.DateOnly); got != "2026-10-24" {
+		t.Errorf("ValidUntil = %s, want 2026-10-24", got)
+	}
+
+	if got := resp.Items[1].Attributes.Campaign.Name; got != "Veckans erbjudande" {
+		t.Errorf("Name = %q, want the campaign string", got)
+	}
+
+	for i, want := range []bool{true, true, false, false, false} {
+		if got := resp.Items[i].Attributes.OnOffer(); got != want {
+			t.Errorf("item %d: OnOffer() = %v, want %v", resp.Items[i].ID, got, want)
+		}
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"errors"
 	"os"
 	"path/filepath"
+	"time"
 )
 
 const (
@@ -12,11 +13,24 @@ const (
 	configFile = "session.json"
 )
 
+// SessionLifetime is roughly how long Mathem keeps a session alive
+const SessionLifetime = 30 * 24 * time.Hour
+
 // Session represents stored session data
 type Session struct {
-	SessionID string `json:"session_id"`
-	CSRFToken string `json:"csrf_token"`
-	Email     string `json:"email"`
+	SessionID string    `json:"session_id"`
+	CSRFToken string    `json:"csrf_token"`
+	Email     string    `json:"email"`
+	CreatedAt time.Time `json:"created_at,omitzero"`
+}
+
+// ExpiresAt estimates when the session expires, from SessionLifetime. It is
+// zero for sessions saved before creation times were recorded.
+func (s *Session) ExpiresAt() time.Time {
+	if s.CreatedAt.IsZero() {
+		return time.Time{}
+	}
+	return s.CreatedAt.Add(SessionLifetime)
 }
 
 // ConfigPath returns the path to the config directory
@@ -0,0 +1,224 @@
+package mathemtest
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+// Product builds a catalog product with the fields the CLI displays
+func Product(id int, name, brand, nameExtra, price, unitPrice, unit string) api.Product {
+	fullName := name
+	if brand != "" {
+		fullName = brand + " " + name
+	}
+
+	return api.Product{
+		ID:   id,
+		Type: "product",
+		Attributes: api.ProductAttributes{
+			Name:                  name,
+			FullName:              fullName,
+			Brand:                 brand,
+			NameExtra:             nameExtra,
+			GrossPrice:            mustParseMoney(price),
+			GrossUnitPrice:        mustParseMoney(unitPrice),
+			UnitPriceQuantityAbbr: unit,
+			Currency:              "SEK",
+			Availability: api.Availability{
+				IsAvailable: true,
+				Description: "I lager",
+				Code:        "available",
+			},
+		},
+	}
+}
+
+// mustParseMoney parses a price from test data; an empty string is zero
+func mustParseMoney(s string) api.Money {
+	if s == "" {
+		return api.Money{}
+	}
+	m, err := api.ParseMoney(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+// WithCampaign puts a product on offer for a week from today
+func WithCampaign(p api.Product, label, originalPrice string, multiBuy *api.MultiBuy) api.Product {
+	now := time.Now().In(SlotZone)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, SlotZone)
+	p.Attributes.Campaign = &api.Campaign{
+		Name:          "Veckans erbjudande",
+		DiscountLabel: label,
+		OriginalPrice: mustParseMoney(originalPrice),
+		MultiBuy:      multiBuy,
+		ValidFrom:     today,
+		ValidUntil:    today.AddDate(0, 0, 7),
+	}
+	return p
+}
+
+// SampleCategories maps SampleProducts to store departments. Products
+// without a department are grouped as "Övrigt".
+func SampleCategories() map[int]string {
+	categories := map[int]string{
+		2352: "Ost",
+		5501: "Frukt & grönt",
+		5502: "Mejeri & ägg",
+	}
+	for _, id := range []int{3681, 3682, 3683, 3690} {
+		categories[id] = "Mejeri & ägg"
+	}
+	for _, id := range []int{4410, 4411, 4420, 4430} {
+		categories[id] = "Kaffe & te"
+	}
+	return categories
+}
+
+// SampleProducts returns the catalog a new Server starts with. Gouda is
+// 20% off and Zoégas coffee is on a multi-buy offer.
+func SampleProducts() []api.Product {
+	outOfStock := Product(3690, "Färsk Standardmjölk 3%", "Arla Ko®", "1,5 l", "21.95", "14.63", "l")
+	outOfStock.Attributes.Availability = api.Availability{
+		IsAvailable: false,
+		Description: "Tillfälligt slut",
+		Code:        "out_of_stock",
+	}
+
+	return []api.Product{
+		Product(3681, "Färsk Mellanmjölk 1,5%", "Arla Ko®", "1,5 l", "19.95", "13.30", "l"),
+		Product(3682, "Färsk Lättmjölk 0,5%", "Arla Ko®", "1 l", "14.50", "14.50", "l"),
+		Product(3683, "Mellanmjölk Ekologisk 1,5%", "Garant Eko", "1 l", "16.95", "16.95", "l"),
+		outOfStock,
+		WithCampaign(Product(2352, "Gouda 28%", "Arla", "ca 1,1 kg", "111.20", "101.09", "kg"), "-20%", "139.00", nil),
+		WithCampaign(Product(4410, "Bryggkaffe Mellanrost", "Zoégas", "450 g", "64.95", "144.33", "kg"), "2 för 110 kr", "", &api.MultiBuy{Quantity: 2, Price: api.Kronor(110)}),
+		WithCampaign(Product(4411, "Bryggkaffe Skånerost", "Zoégas", "450 g", "64.95", "144.33", "kg"), "2 för 110 kr", "", &api.MultiBuy{Quantity: 2, Price: api.Kronor(110)}),
+		Product(4420, "Kaffe Mörkrost Hela Bönor", "Löfbergs", "1 kg", "139.00", "139.00", "kg"),
+		Product(4430, "Brygg Kaffe Ekologiskt", "Garant Eko", "500 g", "59.95", "119.90", "kg"),
+		Product(5501, "Bananer", "", "ca 180 g", "4.32", "24.00", "kg"),
+		Product(5502, "Ägg 12-pack Frigående", "Kronägg", "12 st", "49.95", "4.16", "st"),
+	}
+}
+
+// Slot builds a delivery window starting at the given hour on day
+func Slot(day time.Time, startHour, hours int, fee string, available, eco bool) api.DeliverySlot {
+	start := time.Date(day.Year(), day.Month(), day.Day(), startHour, 0, 0, 0, day.Location())
+	return api.DeliverySlot{
+		ID:          start.Format("20060102-1504"),
+		StartTime:   start,
+		EndTime:     start.Add(time.Duration(hours) * time.Hour),
+		DeliveryFee: mustParseMoney(fee),
+		IsAvailable: available,
+		IsEco:       eco,
+	}
+}
+
+// SlotZone is the time zone of sample delivery slots, Swedish summer time
+var SlotZone = time.FixedZone("CEST", 2*60*60)
+
+// SampleSlots returns delivery days starting the day after from. The
+// evening window on the first day is fully booked.
+func SampleSlots(from time.Time, days int) []api.DeliveryDay {
+	from = from.In(SlotZone)
+
+	var out []api.DeliveryDay
+	for i := range days {
+		day := from.AddDate(0, 0, i+1)
+		out = append(out, api.DeliveryDay{
+			Date: day.Format(time.DateOnly),
+			Slots: []api.DeliverySlot{
+				Slot(day, 8, 2, "49.00", true, false),
+				Slot(day, 10, 2, "39.00", true, true),
+				Slot(day, 13, 2, "29.00", true, false),
+				Slot(day, 17, 2, "59.00", i > 0, false),
+				Slot(day, 19, 2, "49.00", true, true),
+			},
+		})
+	}
+	return out
+}
+
+// SamplePerks returns the perks a new Server starts with: free delivery
+// over 800 kr (active), a coffee discount (available) and a fruit discount
+// (active) that ends today
+func SamplePerks() []api.Perk {
+	now := time.Now().In(SlotZone)
+	endOfMonth := time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, SlotZone).Add(-time.Second)
+	endOfDay := time.Date(now.Year(), now.Month(), now.Day(), 23, 59, 59, 0, SlotZone)
+
+	return []api.Perk{
+		{
+			ID:            101,
+			Title:         "Fri leverans",
+			Description:   "Ingen leveransavgift på din nästa order",
+			Conditions:    "Handla för minst 800 kr",
+			MinimumAmount: api.Kronor(800),
+			IsActive:      true,
+			ExpiresAt:     endOfMonth,
+		},
+		{
+			ID:          102,
+			Title:       "20 kr rabatt på Zoégas",
+			Description: "20 kr rabatt när du köper Zoégas bryggkaffe",
+			Conditions:  "Gäller Zoégas Mellanrost och Skånerost",
+			ProductIDs:  []int{4410, 4411},
+			ExpiresAt:   endOfMonth,
+		},
+		{
+			ID:            103,
+			Title:         "10% på bananer",
+			Description:   "10% rabatt på bananer",
+			Conditions:    "Handla för minst 200 kr",
+			MinimumAmount: api.Kronor(200),
+			ProductIDs:    []int{5501},
+			IsActive:      true,
+			ExpiresAt:     endOfDay,
+		},
+	}
+}
+
+// Detail builds the product page for a catalog product, with the details
+// search results leave out empty
+func Detail(p api.Product) api.ProductDetail {
+	return api.ProductDetail{
+		ID:                p.ID,
+		ProductAttributes: p.Attributes,
+		AbsoluteURL:       fmt.Sprintf("/se/products/%d/", p.ID),
+	}
+}
+
+// SampleDetails returns product pages with full details for some of
+// SampleProducts; the rest only have what Detail fills in
+func SampleDetails() []api.ProductDetail {
+	products := SampleProducts()
+	find := func(id int) api.Product {
+		i := slices.IndexFunc(products, func(p api.Product) bool { return p.ID == id })
+		return products[i]
+	}
+
+	milk := Detail(find(3683))
+	milk.Description = "Ekologisk mellanmjölk från svenska gårdar."
+	milk.Ingredients = "Pastöriserad ekologisk mellanmjölk, vitamin D."
+	milk.Nutrition = api.NutritionFacts{
+		Basis: "per 100 ml",
+		Values: []api.NutritionValue{
+			{Name: "Energi", Value: "196 kJ / 47 kcal"},
+			{Name: "Fett", Value: "1,5 g"},
+			{Name: "Kolhydrater", Value: "4,8 g"},
+			{Name: "Protein", Value: "3,5 g"},
+			{Name: "Salt", Value: "0,1 g"},
+		},
+	}
+	milk.Allergens = []string{"Mjölk"}
+	milk.CountryOfOrigin = "Sverige"
+	milk.Labels = []string{"Ekologisk", "KRAV"}
+	milk.EAN = "7340011488472"
+	milk.Storage = "Förvaras kylt, högst +8°C."
+
+	return []api.ProductDetail{milk}
+}
@@ -0,0 +1,920 @@
+// Package mathemtest provides an in-process fake of the Mathem web and API
+// endpoints used by the client, for offline integration tests.
+package mathemtest
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/thepsadmin/mathemcli/internal/api"
+)
+
+// APIPrefix is the path under which the fake serves the API
+const APIPrefix = "/tienda-web-api/v1"
+
+// Credentials of the user every new server accepts
+const (
+	Email    = "test@example.com"
+	Password = "hunter2"
+)
+
+// MinimumOrder is the smallest cart total that can be ordered
+var MinimumOrder = api.Kronor(300)
+
+// ReservationHold is how long a reserved delivery slot is held
+const ReservationHold = time.Hour
+
+// MaxSuggestions is the number of suggestions returned unless the request
+// asks for a different number with the items parameter
+const MaxSuggestions = 5
+
+// DefaultPageSize is the number of search results per page unless the
+// request asks for a different number with the items parameter
+const DefaultPageSize = 20
+
+// Failure describes an error response injected with Server.Fail
+type Failure struct {
+	// Status is the HTTP status code to respond with
+	Status int
+	// Body is the response body; defaults to an error envelope
+	Body string
+	// RetryAfter is sent as the Retry-After header if set
+	RetryAfter string
+	// Times is how many requests fail before the endpoint recovers (default 1)
+	Times int
+}
+
+// cartLine is a product in the fake cart
+type cartLine struct {
+	itemID    int
+	productID int
+	quantity  int
+}
+
+// order is a placed order in the fake
+type order struct {
+	meta  api.Order
+	lines []api.CartItem
+}
+
+// Server is a fake Mathem server with in-memory state. It is safe for
+// concurrent use.
+type Server struct {
+	*httptest.Server
+
+	mu        sync.Mutex
+	users     map[string]string
+	sessions  map[string]string
+	products  []api.Product
+	cart      []cartLine
+	nextItem  int
+	onlyAdds  bool
+	slots     []api.DeliveryDay
+	reserved  *api.SlotReservation
+	limits    map[int]int
+	perks     []api.Perk
+	details   map[int]api.ProductDetail
+	category  map[int]string
+	orders    []order
+	nextOrder int
+	failures  map[string][]Failure
+	requests  map[string]int
+}
+
+// NewServer starts a fake server seeded with SampleProducts and their
+// SampleDetails, SamplePerks, two weeks of SampleSlots from tomorrow, a
+// past and an upcoming order, and a user that can log in with Email and
+// Password. Call Close when done.
+func NewServer() *Server {
+	s := &Server{
+		users:     map[string]string{Email: Password},
+		sessions:  make(map[string]string),
+		products:  SampleProducts(),
+		nextItem:  1000,
+		limits:    make(map[int]int),
+		perks:     SamplePerks(),
+		details:   make(map[int]api.ProductDetail),
+		category:  SampleCategories(),
+		nextOrder: 5000,
+		slots:     SampleSlots(time.Now(), 14),
+		failures:  make(map[string][]Failure),
+		requests:  make(map[string]int),
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /se/user/login/", s.handleLoginPage)
+	mux.HandleFunc("POST "+APIPrefix+"/user/login/", s.handleLogin)
+	mux.HandleFunc("GET "+APIPrefix+"/search/mixed/", s.authenticated(s.handleSearch))
+	mux.HandleFunc("GET "+APIPrefix+"/products/{id}/", s.authenticated(s.handleProduct))
+	mux.HandleFunc("GET "+APIPrefix+"/campaigns/promoted_products/", s.authenticated(s.handlePromotedProducts))
+	mux.HandleFunc("GET "+APIPrefix+"/cart/", s.authenticated(s.handleGetCart))
+	mux.HandleFunc("POST "+APIPrefix+"/cart/items/", s.authenticated(s.handleAddItems))
+	mux.HandleFunc("POST "+APIPrefix+"/cart/clear/", s.authenticated(s.handleClearCart))
+	mux.HandleFunc("GET "+APIPrefix+"/cart/validate/", s.authenticated(s.handleValidateCart))
+	mux.HandleFunc("GET "+APIPrefix+"/orders/", s.authenticated(s.handleOrders))
+	mux.HandleFunc("GET "+APIPrefix+"/orders/{id}/", s.authenticated(s.handleOrder))
+	mux.HandleFunc("GET "+APIPrefix+"/perks/", s.authenticated(s.handlePerks))
+	mux.HandleFunc("GET "+APIPrefix+"/slot-picker/slots/", s.authenticated(s.handleSlots))
+	mux.HandleFunc("POST "+APIPrefix+"/slot-picker/reservations/", s.authenticated(s.handleReserveSlot))
+	mux.HandleFunc("GET "+APIPrefix+"/slot-picker/reservations/current/", s.authenticated(s.handleGetReservation))
+	mux.HandleFunc("DELETE "+APIPrefix+"/slot-picker/reservations/current/", s.authenticated(s.handleReleaseSlot))
+
+	for _, d := range SampleDetails() {
+		s.details[d.ID] = d
+	}
+
+	// Last week's delivered order, which has a product that is now out of
+	// stock, and an upcoming one
+	now := time.Now().In(SlotZone)
+	lastWeek := time.Date(now.Year(), now.Month(), now.Day()-7, 17, 0, 0, 0, SlotZone)
+	s.AddOrder("delivered", lastWeek,
+		api.CartItem{ProductID: 3681, Quantity: 2},
+		api.CartItem{ProductID: 4410, Quantity: 1},
+		api.CartItem{ProductID: 3690, Quantity: 1},
+		api.CartItem{ProductID: 5501, Quantity: 6},
+	)
+	s.AddOrder("confirmed", lastWeek.AddDate(0, 0, 9),
+		api.CartItem{ProductID: 2352, Quantity: 1},
+	)
+
+	s.Server = httptest.NewServer(s.intercept(mux))
+	return s
+}
+
+// APIURL returns the API base URL of the fake
+func (s *Server) APIURL() string {
+	return s.URL + APIPrefix
+}
+
+// ClientOptions returns options that point an api.Client at the fake. The
+// fake is in-process, so the client's rate limit is lifted.
+func (s *Server) ClientOptions() []api.Option {
+	return []api.Option{
+		api.WithBaseURL(s.APIURL()),
+		api.WithWebBaseURL(s.URL),
+		api.WithRateLimit(api.NoRateLimit),
+	}
+}
+
+// AddUser allows another user to log in
+func (s *Server) AddUser(email, password string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.users[email] = password
+}
+
+// SetProductDetail sets the product page of a catalog product. Products
+// without one get a page built by Detail.
+func (s *Server) SetProductDetail(d api.ProductDetail) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.details[d.ID] = d
+}
+
+// AddOrder adds an order for products in the catalog, delivered in a
+// two-hour window from delivery, and returns its ID. Orders with a status
+// other than "delivered" or "cancelled" are upcoming.
+func (s *Server) AddOrder(status string, delivery time.Time, lines ...api.CartItem) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.nextOrder++
+	o := order{
+		meta: api.Order{
+			ID:            s.nextOrder,
+			OrderNumber:   fmt.Sprintf("M%d", 100000+s.nextOrder),
+			Status:        status,
+			IsUpcoming:    status != "delivered" && status != "cancelled",
+			CreatedAt:     delivery.AddDate(0, 0, -2),
+			DeliveryStart: delivery,
+			DeliveryEnd:   delivery.Add(2 * time.Hour),
+		},
+		lines: lines,
+	}
+	s.orders = append(s.orders, o)
+	return o.meta.ID
+}
+
+// SetCategory puts a product in a store department, for carts grouped by
+// category
+func (s *Server) SetCategory(productID int, name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.category[productID] = name
+}
+
+// AddProducts adds products to the catalog, replacing any with the same ID
+func (s *Server) AddProducts(products ...api.Product) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, p := range products {
+		s.products = slices.DeleteFunc(s.products, func(existing api.Product) bool {
+			return existing.ID == p.ID
+		})
+		s.products = append(s.products, p)
+	}
+}
+
+// SetQuantityLimit sets the most of a product a single order may contain
+func (s *Server) SetQuantityLimit(productID, max int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.limits[productID] = max
+}
+
+// SetDeliverySlots replaces the delivery days offered by the slot picker
+func (s *Server) SetDeliverySlots(days ...api.DeliveryDay) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.slots = days
+}
+
+// ReservedSlot returns the ID of the reserved delivery slot, or "" if none
+// is held
+func (s *Server) ReservedSlot() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if r := s.reservationLocked(); r != nil {
+		return r.Slot.ID
+	}
+	return ""
+}
+
+// NewSession creates a logged-in session for Email and returns its ID, for
+// tests that don't want to go through the login flow
+func (s *Server) NewSession() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.newSessionLocked(Email)
+}
+
+// ExpireSessions invalidates every session, as if they had timed out
+func (s *Server) ExpireSessions() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	clear(s.sessions)
+}
+
+// Fail makes the next request(s) to path fail. The path is relative to the
+// API prefix for API endpoints (e.g. "/cart/items/") and absolute for web
+// pages (e.g. "/se/user/login/").
+func (s *Server) Fail(path string, f Failure) {
+	if f.Times <= 0 {
+		f.Times = 1
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures[path] = append(s.failures[path], f)
+}
+
+// Requests returns how many requests were made to path, including failed ones
+func (s *Server) Requests(path string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests[path]
+}
+
+// CartQuantity returns the quantity of a product in the cart
+func (s *Server) CartQuantity(productID int) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, line := range s.cart {
+		if line.productID == productID {
+			return line.quantity
+		}
+	}
+	return 0
+}
+
+// intercept counts requests and serves injected failures before routing
+func (s *Server) intercept(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, APIPrefix)
+
+		s.mu.Lock()
+		s.requests[path]++
+		var failure *Failure
+		if queue := s.failures[path]; len(queue) > 0 {
+			f := queue[0]
+			failure = &f
+			queue[0].Times--
+			if queue[0].Times == 0 {
+				s.failures[path] = queue[1:]
+			}
+		}
+		s.mu.Unlock()
+
+		if failure == nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		if failure.RetryAfter != "" {
+			w.Header().Set("Retry-After", failure.RetryAfter)
+		}
+		if failure.Body != "" {
+			w.WriteHeader(failure.Status)
+			fmt.Fprint(w, failure.Body)
+			return
+		}
+		writeErrors(w, failure.Status, http.StatusText(failure.Status))
+	})
+}
+
+// authenticated rejects requests without a valid session cookie
+func (s *Server) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		cookie, err := r.Cookie("sessionid")
+
+		s.mu.Lock()
+		_, ok := s.sessions[cookieValue(cookie, err)]
+		s.mu.Unlock()
+
+		if !ok {
+			writeErrors(w, http.StatusForbidden, "Authentication credentials were not provided.")
+			return
+		}
+		next(w, r)
+	}
+}
+
+func (s *Server) handleLoginPage(w http.ResponseWriter, r *http.Request) {
+	http.SetCookie(w, &http.Cookie{Name: "csrftoken", Value: randomToken(), Path: "/"})
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	fmt.Fprint(w, "<!doctype html><title>Logga in | Mathem</title>")
+}
+
+func (s *Server) handleLogin(w http.ResponseWriter, r *http.Request) {
+	// Like the real site, refuse logins that skipped the login page
+	if _, err := r.Cookie("csrftoken"); err != nil {
+		writeErrors(w, http.StatusForbidden, "CSRF Failed: CSRF cookie not set.")
+		return
+	}
+
+	var payload struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.mu.Lock()
+	password, ok := s.users[payload.Username]
+	if !ok || password != payload.Password {
+		s.mu.Unlock()
+		writeErrors(w, http.StatusBadRequest, "Felaktig e-postadress eller lösenord")
+		return
+	}
+	sessionID := s.newSessionLocked(payload.Username)
+	s.mu.Unlock()
+
+	http.SetCookie(w, &http.Cookie{Name: "sessionid", Value: sessionID, Path: "/", HttpOnly: true})
+	writeJSON(w, http.StatusOK, map[string]string{"email": payload.Username})
+}
+
+func (s *Server) handleSearch(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	if query.Get("type") == "suggestion" {
+		s.handleSuggest(w, r)
+		return
+	}
+
+	q := strings.ToLower(query.Get("q"))
+	page := max(atoiDefault(query.Get("page"), 1), 1)
+	perPage := max(atoiDefault(query.Get("items"), DefaultPageSize), 1)
+
+	s.mu.Lock()
+	var matches []api.Product
+	for _, p := range s.products {
+		text := strings.ToLower(p.Attributes.FullName + " " + p.Attributes.Name + " " + p.Attributes.Brand)
+		if strings.Contains(text, q) {
+			matches = append(matches, p)
+		}
+	}
+	s.mu.Unlock()
+
+	start := min((page-1)*perPage, len(matches))
+	end := min(start+perPage, len(matches))
+
+	writeJSON(w, http.StatusOK, api.SearchResponse{
+		Type: "product",
+		Attributes: api.SearchAttributes{
+			Items:        len(matches),
+			Page:         page,
+			HasMoreItems: end < len(matches),
+		},
+		Items: matches[start:end],
+	})
+}
+
+// handleSuggest suggests words from product names that start with the
+// query, or are a typo or two away from it
+func (s *Server) handleSuggest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	q := strings.ToLower(strings.TrimSpace(query.Get("q")))
+	limit := max(atoiDefault(query.Get("items"), MaxSuggestions), 1)
+
+	// Allow a typo per four letters, at most two
+	typos := min(utf8.RuneCountInString(q)/4, 2)
+
+	type candidate struct {
+		text     string
+		distance int
+	}
+	var candidates []candidate
+	seen := make(map[string]bool)
+
+	s.mu.Lock()
+	for _, p := range s.products {
+		for _, word := range strings.Fields(strings.ToLower(p.Attributes.Name + " " + p.Attributes.Brand)) {
+			if seen[word] || !isWord(word) {
+				continue
+			}
+			seen[word] = true
+
+			switch d := levenshtein(q, word); {
+			case strings.HasPrefix(word, q):
+				candidates = append(candidates, candidate{word, 0})
+			case d <= typos:
+				candidates = append(candidates, candidate{word, d})
+			}
+		}
+	}
+	s.mu.Unlock()
+
+	slices.SortFunc(candidates, func(a, b candidate) int {
+		if a.distance != b.distance {
+			return a.distance - b.distance
+		}
+		return strings.Compare(a.text, b.text)
+	})
+
+	items := []api.Suggestion{}
+	for _, c := range candidates[:min(limit, len(candidates))] {
+		items = append(items, api.Suggestion{
+			Type:       "suggestion",
+			Attributes: api.SuggestionAttributes{Text: c.text},
+		})
+	}
+	writeJSON(w, http.StatusOK, api.SuggestionResponse{Type: "suggestion", Items: items})
+}
+
+func (s *Server) handleProduct(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	p, ok := s.productLocked(id)
+	if err != nil || !ok {
+		writeErrors(w, http.StatusNotFound, "Produkten finns inte")
+		return
+	}
+
+	detail, ok := s.details[id]
+	if !ok {
+		detail = Detail(p)
+	}
+	writeJSON(w, http.StatusOK, detail)
+}
+
+func (s *Server) handlePromotedProducts(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	promoted := []api.Product{}
+	for _, p := range s.products {
+		if p.Attributes.OnOffer() {
+			promoted = append(promoted, p)
+		}
+	}
+	writeJSON(w, http.StatusOK, api.PromotedProducts{Items: promoted})
+}
+
+func (s *Server) handleGetCart(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, s.cartLocked(api.CartGrouping(r.URL.Query().Get("group_by"))))
+}
+
+// IgnoreNegativeQuantities makes /cart/items/ drop negative quantities
+// instead of decreasing the cart, to test how the CLI copes if the real
+// endpoint only ever adds
+func (s *Server) IgnoreNegativeQuantities() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.onlyAdds = true
+}
+
+func (s *Server) handleAddItems(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		Items []api.CartItem `json:"items"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, item := range payload.Items {
+		if _, ok := s.productLocked(item.ProductID); !ok {
+			writeFieldError(w, "product_id", fmt.Sprintf("Product %d does not exist", item.ProductID), "does_not_exist")
+			return
+		}
+	}
+
+	// Quantities are additive, like the real endpoint. That negative ones
+	// decrease is an assumption; IgnoreNegativeQuantities drops them.
+	for _, item := range payload.Items {
+		if item.Quantity < 0 && s.onlyAdds {
+			continue
+		}
+		i := slices.IndexFunc(s.cart, func(line cartLine) bool { return line.productID == item.ProductID })
+		if i < 0 {
+			s.nextItem++
+			s.cart = append(s.cart, cartLine{itemID: s.nextItem, productID: item.ProductID})
+			i = len(s.cart) - 1
+		}
+		s.cart[i].quantity += item.Quantity
+	}
+	s.cart = slices.DeleteFunc(s.cart, func(line cartLine) bool { return line.quantity <= 0 })
+
+	writeJSON(w, http.StatusOK, s.cartLocked(api.GroupByRecipes))
+}
+
+func (s *Server) handleClearCart(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.cart = nil
+	writeJSON(w, http.StatusOK, s.cartLocked(api.GroupByRecipes))
+}
+
+func (s *Server) handleOrders(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	orders := []api.Order{}
+	for _, o := range slices.Backward(s.orders) {
+		rendered := s.orderLocked(o)
+		rendered.Lines = nil
+		orders = append(orders, rendered)
+	}
+	writeJSON(w, http.StatusOK, api.Orders{Items: orders})
+}
+
+func (s *Server) handleOrder(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := slices.IndexFunc(s.orders, func(o order) bool { return o.meta.ID == id })
+	if err != nil || i < 0 {
+		writeErrors(w, http.StatusNotFound, "Ordern finns inte")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.orderLocked(s.orders[i]))
+}
+
+// orderLocked renders an order with its lines and totals, and the current
+// availability of its products. s.mu must be held.
+func (s *Server) orderLocked(o order) api.Order {
+	rendered := o.meta
+	rendered.Lines = []api.OrderLine{}
+	for _, line := range o.lines {
+		p, ok := s.productLocked(line.ProductID)
+		availability := p.Attributes.Availability
+		if !ok {
+			availability = api.Availability{Description: "Utgått", Code: "discontinued"}
+		}
+
+		lineTotal := p.Attributes.GrossPrice.Mul(line.Quantity)
+		rendered.Lines = append(rendered.Lines, api.OrderLine{
+			Product: api.CartProduct{
+				ID:         line.ProductID,
+				FullName:   p.Attributes.FullName,
+				Brand:      p.Attributes.Brand,
+				Name:       p.Attributes.Name,
+				NameExtra:  p.Attributes.NameExtra,
+				GrossPrice: p.Attributes.GrossPrice,
+				Currency:   p.Attributes.Currency,
+			},
+			Quantity:     line.Quantity,
+			DisplayPrice: lineTotal,
+			Availability: availability,
+		})
+		rendered.ProductQuantityCount += line.Quantity
+		rendered.TotalGrossAmount = rendered.TotalGrossAmount.Add(lineTotal)
+	}
+	return rendered
+}
+
+func (s *Server) handlePerks(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	writeJSON(w, http.StatusOK, api.Perks{Items: s.perks})
+}
+
+func (s *Server) handleSlots(w http.ResponseWriter, r *http.Request) {
+	days := max(atoiDefault(r.URL.Query().Get("num-days"), 3), 1)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	writeJSON(w, http.StatusOK, api.DeliverySlots{Days: s.slots[:min(days, len(s.slots))]})
+}
+
+func (s *Server) handleReserveSlot(w http.ResponseWriter, r *http.Request) {
+	var payload struct {
+		SlotID string `json:"slot_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+		writeErrors(w, http.StatusBadRequest, "Invalid JSON")
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	slot, ok := s.slotLocked(payload.SlotID)
+	switch {
+	case !ok:
+		writeFieldError(w, "slot_id", fmt.Sprintf("Slot %q does not exist", payload.SlotID), "does_not_exist")
+		return
+	case !slot.IsAvailable:
+		writeFieldError(w, "slot_id", "Tiden är fullbokad", "slot_full")
+		return
+	}
+
+	// A new reservation replaces the old one. Like the endpoint itself,
+	// this is assumed rather than observed.
+	s.reserved = &api.SlotReservation{Slot: slot, ExpiresAt: time.Now().Add(ReservationHold)}
+	writeJSON(w, http.StatusOK, s.reserved)
+}
+
+func (s *Server) handleGetReservation(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	reservation := s.reservationLocked()
+	if reservation == nil {
+		writeErrors(w, http.StatusNotFound, "No reserved slot")
+		return
+	}
+	writeJSON(w, http.StatusOK, reservation)
+}
+
+func (s *Server) handleReleaseSlot(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.reservationLocked() == nil {
+		writeErrors(w, http.StatusNotFound, "No reserved slot")
+		return
+	}
+	s.reserved = nil
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// slotLocked looks up a delivery slot by ID. s.mu must be held.
+func (s *Server) slotLocked(id string) (api.DeliverySlot, bool) {
+	for _, day := range s.slots {
+		for _, slot := range day.Slots {
+			if slot.ID == id {
+				return slot, true
+			}
+		}
+	}
+	return api.DeliverySlot{}, false
+}
+
+// reservationLocked returns the reservation unless it has expired. s.mu
+// must be held.
+func (s *Server) reservationLocked() *api.SlotReservation {
+	if s.reserved != nil && time.Now().After(s.reserved.ExpiresAt) {
+		s.reserved = nil
+	}
+	return s.reserved
+}
+
+func (s *Server) handleValidateCart(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v api.CartValidation
+	var total api.Money
+	for _, line := range s.cart {
+		p, _ := s.productLocked(line.productID)
+		total = total.Add(p.Attributes.GrossPrice.Mul(line.quantity))
+
+		if !p.Attributes.Availability.IsAvailable {
+			v.UnavailableProducts = append(v.UnavailableProducts, api.UnavailableProduct{
+				ProductID: p.ID,
+				FullName:  p.Attributes.FullName,
+				Reason:    p.Attributes.Availability.Description,
+			})
+		}
+		if limit, ok := s.limits[p.ID]; ok && line.quantity > limit {
+			v.QuantityLimits = append(v.QuantityLimits, api.QuantityLimit{
+				ProductID:   p.ID,
+				FullName:    p.Attributes.FullName,
+				Quantity:    line.quantity,
+				MaxQuantity: limit,
+			})
+		}
+	}
+
+	switch {
+	case len(s.cart) == 0:
+		v.Errors = append(v.Errors, api.ValidationError{Code: "empty_cart", Message: "Varukorgen är tom"})
+	case total.Less(MinimumOrder):
+		v.MinimumOrder = &api.MinimumOrder{MinimumAmount: MinimumOrder, CurrentAmount: total}
+	}
+	if s.reservationLocked() == nil {
+		v.Errors = append(v.Errors, api.ValidationError{Code: "no_delivery_slot", Message: "Välj en leveranstid"})
+	}
+
+	v.IsValid = len(v.UnavailableProducts) == 0 && len(v.QuantityLimits) == 0 &&
+		v.MinimumOrder == nil && len(v.Errors) == 0
+	writeJSON(w, http.StatusOK, v)
+}
+
+// cartLocked renders the cart in the API's format. Grouped by recipes,
+// everything is in an unnamed group since the fake has no recipes. s.mu
+// must be held.
+func (s *Server) cartLocked(groupBy api.CartGrouping) api.Cart {
+	var (
+		groups []api.CartGroup
+		count  int
+		total  api.Money
+	)
+	for _, line := range s.cart {
+		p, _ := s.productLocked(line.productID)
+		lineTotal := p.Attributes.GrossPrice.Mul(line.quantity)
+
+		group := api.CartGroup{ID: "other", Type: "other"}
+		switch groupBy {
+		case api.GroupByCategories:
+			name := s.category[p.ID]
+			if name == "" {
+				name = "Övrigt"
+			}
+			group = api.CartGroup{ID: strings.ToLower(name), Name: name, Type: "category"}
+		case api.GroupByNone:
+			group = api.CartGroup{ID: "all", Type: "all"}
+		}
+
+		i := slices.IndexFunc(groups, func(g api.CartGroup) bool { return g.ID == group.ID })
+		if i < 0 {
+			groups = append(groups, group)
+			i = len(groups) - 1
+		}
+		groups[i].Subtotal = groups[i].Subtotal.Add(lineTotal)
+		groups[i].Items = append(groups[i].Items, api.CartGroupItem{
+			Product: api.CartProduct{
+				ID:          p.ID,
+				FullName:    p.Attributes.FullName,
+				Brand:       p.Attributes.Brand,
+				Name:        p.Attributes.Name,
+				NameExtra:   p.Attributes.NameExtra,
+				GrossPrice:  p.Attributes.GrossPrice,
+				Currency:    p.Attributes.Currency,
+				AbsoluteURL: fmt.Sprintf("/se/products/%d/", p.ID),
+			},
+			ItemID:       line.itemID,
+			Quantity:     line.quantity,
+			DisplayPrice: lineTotal,
+		})
+		count += line.quantity
+		total = total.Add(lineTotal)
+	}
+
+	cart := api.Cart{
+		ActiveGrouping:       string(groupBy),
+		LabelText:            fmt.Sprintf("%d varor", count),
+		ProductQuantityCount: count,
+		DisplayPrice:         total,
+		TotalGrossAmount:     total,
+		Currency:             "SEK",
+		Groups:               append([]api.CartGroup{}, groups...),
+		SummaryLines: []api.SummaryGroup{{
+			ID: "total",
+			Lines: []api.SummaryLine{
+				{Name: "products", Description: "Varor", GrossAmount: total},
+				{Name: "total", Description: "Totalt", GrossAmount: total},
+			},
+		}},
+	}
+	return cart
+}
+
+// productLocked looks up a product by ID. s.mu must be held.
+func (s *Server) productLocked(id int) (api.Product, bool) {
+	i := slices.IndexFunc(s.products, func(p api.Product) bool { return p.ID == id })
+	if i < 0 {
+		return api.Product{}, false
+	}
+	return s.products[i], true
+}
+
+// newSessionLocked creates a session for the user. s.mu must be held.
+func (s *Server) newSessionLocked(email string) string {
+	sessionID := randomToken()
+	s.sessions[sessionID] = email
+	return sessionID
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeErrors(w http.ResponseWriter, status int, messages ...string) {
+	writeJSON(w, status, map[string]any{"errors": messages, "field_errors": map[string]any{}})
+}
+
+func writeFieldError(w http.ResponseWriter, field, message, code string) {
+	writeJSON(w, http.StatusBadRequest, map[string]any{
+		"errors":       []string{},
+		"field_errors": map[string]api.FieldError{field: {Message: message, Code: code}},
+	})
+}
+
+func cookieValue(c *http.Cookie, err error) string {
+	if err != nil {
+		return ""
+	}
+	return c.Value
+}
+
+func randomToken() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// isWord reports whether s is worth suggesting, i.e. letters only and not
+// too short
+func isWord(s string) bool {
+	if utf8.RuneCountInString(s) < 3 {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+// levenshtein returns the edit distance between a and b
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+func atoiDefault(s string, def int) int {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
@@ -0,0 +1,230 @@
+// Package packsize parses Mathem's free-text pack size descriptions, such
+// as the NameExtra of products and cart items: "1,5 l", "ca 800 g",
+// "4 x 125 g", "6-pack 33 cl" or "12 st".
+package packsize
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// ErrUnrecognized is returned for descriptions that aren't a pack size
+var ErrUnrecognized = errors.New("unrecognized pack size")
+
+// Unit is a unit of measure
+type Unit string
+
+// Units of mass, volume and count. Kilogram, Litre and Piece are the base
+// units prices are compared in.
+const (
+	Kilogram   Unit = "kg"
+	Hectogram  Unit = "hg"
+	Gram       Unit = "g"
+	Litre      Unit = "l"
+	Decilitre  Unit = "dl"
+	Centilitre Unit = "cl"
+	Millilitre Unit = "ml"
+	Piece      Unit = "st"
+)
+
+// Dimension is what a unit measures
+type Dimension int
+
+const (
+	Mass Dimension = iota + 1
+	Volume
+	Count
+)
+
+// String returns the name of the dimension
+func (d Dimension) String() string {
+	switch d {
+	case Mass:
+		return "mass"
+	case Volume:
+		return "volume"
+	case Count:
+		return "count"
+	}
+	return "unknown"
+}
+
+// units maps each unit to its dimension and how many base units it is
+var units = map[Unit]struct {
+	dimension Dimension
+	factor    float64
+}{
+	Kilogram:   {Mass, 1},
+	Hectogram:  {Mass, 0.1},
+	Gram:       {Mass, 0.001},
+	Litre:      {Volume, 1},
+	Decilitre:  {Volume, 0.1},
+	Centilitre: {Volume, 0.01},
+	Millilitre: {Volume, 0.001},
+	Piece:      {Count, 1},
+}
+
+// unitNames maps the spellings seen in descriptions to units
+var unitNames = map[string]Unit{
+	"kg": Kilogram, "kilo": Kilogram,
+	"hg": Hectogram,
+	"g":  Gram, "gr": Gram, "gram": Gram,
+	"l": Litre, "lit": Litre, "liter": Litre,
+	"dl": Decilitre,
+	"cl": Centilitre,
+	"ml": Millilitre,
+	"st": Piece, "stk": Piece, "styck": Piece, "pcs": Piece,
+}
+
+// ParseUnit parses a unit abbreviation such as "kg", "dl" or "st.", as in
+// a product's unit price
+func ParseUnit(s string) (Unit, error) {
+	name := strings.TrimSuffix(strings.ToLower(strings.TrimSpace(s)), ".")
+	if u, ok := unitNames[name]; ok {
+		return u, nil
+	}
+	return "", fmt.Errorf("%w: unknown unit %q", ErrUnrecognized, s)
+}
+
+// Dimension returns what the unit measures
+func (u Unit) Dimension() Dimension {
+	return units[u].dimension
+}
+
+// Base returns the base unit of the unit's dimension: Kilogram, Litre or
+// Piece
+func (u Unit) Base() Unit {
+	switch u.Dimension() {
+	case Mass:
+		return Kilogram
+	case Volume:
+		return Litre
+	case Count:
+		return Piece
+	}
+	return u
+}
+
+// Quantity is an amount in a unit
+type Quantity struct {
+	Value float64
+	Unit  Unit
+}
+
+// Base returns the quantity in its base unit, e.g. 0.5 kg for 500 g
+func (q Quantity) Base() Quantity {
+	u, ok := units[q.Unit]
+	if !ok {
+		return q
+	}
+	return Quantity{Value: q.Value * u.factor, Unit: q.Unit.Base()}
+}
+
+// In converts the quantity to another unit of the same dimension
+func (q Quantity) In(unit Unit) (Quantity, bool) {
+	if q.Unit.Dimension() == 0 || q.Unit.Dimension() != unit.Dimension() {
+		return Quantity{}, false
+	}
+	return Quantity{Value: q.Base().Value / units[unit].factor, Unit: unit}, true
+}
+
+// String formats the quantity in Swedish style, e.g. "1,5 l"
+func (q Quantity) String() string {
+	value := strconv.FormatFloat(q.Value, 'f', -1, 64)
+	return strings.Replace(value, ".", ",", 1) + " " + string(q.Unit)
+}
+
+// Size is a parsed pack size
+type Size struct {
+	// Count is the number of packs in a multipack, 1 for a single pack
+	Count int
+
+	// Each is the quantity in each pack
+	Each Quantity
+
+	// Approximate is set for sizes that vary, like "ca 800 g" for produce
+	// and cheese sold by weight, or a range like "150-200 g"
+	Approximate bool
+}
+
+// Total returns the quantity of all packs together
+func (s Size) Total() Quantity {
+	return Quantity{Value: s.Each.Value * float64(s.Count), Unit: s.Each.Unit}
+}
+
+// String formats the size, e.g. "ca 800 g" or "4 x 125 g"
+func (s Size) String() string {
+	str := s.Each.String()
+	if s.Count > 1 {
+		str = strconv.Itoa(s.Count) + " x " + str
+	}
+	if s.Approximate {
+		str = "ca " + str
+	}
+	return str
+}
+
+// sizePattern matches a pack size after normalization: an optional "ca",
+// an optional multipack count before the size ("4 x", "6-pack"), the size
+// or a range of sizes with its unit, and an optional multipack count after
+// it ("33 cl 6-pack")
+var sizePattern = regexp.MustCompile(`^(ca\.? ?|cirka ?)?` +
+	`(?:(\d+) ?(?:x|-?pack|-?p) ?)?` +
+	`(\d+(?:\.\d+)?)(?: ?- ?(\d+(?:\.\d+)?))? ?([a-z]+)\.?` +
+	`(?:,? (\d+) ?-?(?:pack|p))?$`)
+
+// decimalComma matches a comma between digits, as in "1,5 l"
+var decimalComma = regexp.MustCompile(`(\d),(\d)`)
+
+// Parse parses a pack size description. Decimal commas, "×" and any
+// letter case are accepted.
+func Parse(s string) (Size, error) {
+	normalized := strings.ToLower(strings.Join(strings.Fields(s), " "))
+	normalized = strings.ReplaceAll(normalized, "×", "x")
+	normalized = decimalComma.ReplaceAllString(normalized, "$1.$2")
+
+	m := sizePattern.FindStringSubmatch(normalized)
+	if m == nil {
+		return Size{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+	}
+	approx, prefixCount, amount, upper, unitName, suffixCount := m[1], m[2], m[3], m[4], m[5], m[6]
+
+	unit, ok := unitNames[unitName]
+	if !ok {
+		return Size{}, fmt.Errorf("%w: unknown unit %q in %q", ErrUnrecognized, unitName, s)
+	}
+
+	value, err := strconv.ParseFloat(amount, 64)
+	if err != nil || value <= 0 {
+		return Size{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+	}
+
+	size := Size{Count: 1, Approximate: approx != ""}
+
+	// A range like "150-200 g" is taken as its midpoint
+	if upper != "" {
+		high, err := strconv.ParseFloat(upper, 64)
+		if err != nil || high < value {
+			return Size{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+		}
+		value = (value + high) / 2
+		size.Approximate = true
+	}
+	size.Each = Quantity{Value: value, Unit: unit}
+
+	if prefixCount != "" && suffixCount != "" {
+		return Size{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+	}
+	if count := prefixCount + suffixCount; count != "" {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return Size{}, fmt.Errorf("%w: %q", ErrUnrecognized, s)
+		}
+		size.Count = n
+	}
+
+	return size, nil
+}
@@ -0,0 +1,96 @@
+package packsize
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Size
+	}{
+		{"1,5 l", Size{Count: 1, Each: Quantity{1.5, Litre}}},
+		{"500 g", Size{Count: 1, Each: Quantity{500, Gram}}},
+		{"1 kg", Size{Count: 1, Each: Quantity{1, Kilogram}}},
+		{"12 st", Size{Count: 1, Each: Quantity{12, Piece}}},
+		{"ca 800 g", Size{Count: 1, Each: Quantity{800, Gram}, Approximate: true}},
+		{"ca. 1,1 kg", Size{Count: 1, Each: Quantity{1.1, Kilogram}, Approximate: true}},
+		{"Ca 180 g", Size{Count: 1, Each: Quantity{180, Gram}, Approximate: true}},
+		{"4 x 125 g", Size{Count: 4, Each: Quantity{125, Gram}}},
+		{"4x125g", Size{Count: 4, Each: Quantity{125, Gram}}},
+		{"6 × 1,5 l", Size{Count: 6, Each: Quantity{1.5, Litre}}},
+		{"6-pack 33 cl", Size{Count: 6, Each: Quantity{33, Centilitre}}},
+		{"6p 33cl", Size{Count: 6, Each: Quantity{33, Centilitre}}},
+		{"33 cl, 6-pack", Size{Count: 6, Each: Quantity{33, Centilitre}}},
+		{"150-200 g", Size{Count: 1, Each: Quantity{175, Gram}, Approximate: true}},
+		{"  2  dl ", Size{Count: 1, Each: Quantity{2, Decilitre}}},
+		{"10 st.", Size{Count: 1, Each: Quantity{10, Piece}}},
+		{"1 liter", Size{Count: 1, Each: Quantity{1, Litre}}},
+	}
+
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	for _, in := range []string{"", "Eko", "ca", "500", "2 burkar", "0 g", "4 x 125 g, 6-pack", "200-150 g"} {
+		if _, err := Parse(in); !errors.Is(err, ErrUnrecognized) {
+			t.Errorf("Parse(%q) error = %v, want ErrUnrecognized", in, err)
+		}
+	}
+}
+
+func TestTotalAndBase(t *testing.T) {
+	size, err := Parse("4 x 125 g")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	total := size.Total()
+	if total != (Quantity{500, Gram}) {
+		t.Errorf("Total = %v, want 500 g", total)
+	}
+	if base := total.Base(); base.Unit != Kilogram || math.Abs(base.Value-0.5) > 1e-9 {
+		t.Errorf("Base = %v, want 0,5 kg", base)
+	}
+
+	if q, ok := (Quantity{1.5, Litre}).In(Decilitre); !ok || math.Abs(q.Value-15) > 1e-9 {
+		t.Errorf("In(dl) = %v, %v, want 15 dl", q, ok)
+	}
+	if _, ok := (Quantity{1, Kilogram}).In(Litre); ok {
+		t.Error("converted mass to volume")
+	}
+}
+
+func TestString(t *testing.T) {
+	for _, in := range []string{"1,5 l", "ca 800 g", "4 x 125 g"} {
+		size, err := Parse(in)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := size.String(); got != in {
+			t.Errorf("String() = %q, want %q", got, in)
+		}
+	}
+}
+
+func TestParseUnit(t *testing.T) {
+	for in, want := range map[string]Unit{"kg": Kilogram, "St.": Piece, " l ": Litre, "hg": Hectogram} {
+		if got, err := ParseUnit(in); err != nil || got != want {
+			t.Errorf("ParseUnit(%q) = %q, %v, want %q", in, got, err, want)
+		}
+	}
+	if _, err := ParseUnit("förp"); !errors.Is(err, ErrUnrecognized) {
+		t.Errorf("ParseUnit(förp) error = %v", err)
+	}
+}
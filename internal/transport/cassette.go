@@ -0,0 +1,233 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// CassetteFile is the name of the file interactions are saved to
+const CassetteFile = "cassette.json"
+
+// Cassette is a set of recorded HTTP interactions
+type Cassette struct {
+	Interactions []Interaction `json:"interactions"`
+}
+
+// Interaction is a single recorded request and its response
+type Interaction struct {
+	Request  RecordedRequest  `json:"request"`
+	Response RecordedResponse `json:"response"`
+}
+
+// RecordedRequest is the request half of an interaction
+type RecordedRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// RecordedResponse is the response half of an interaction
+type RecordedResponse struct {
+	Status  int         `json:"status"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// LoadCassette reads the cassette saved in dir
+func LoadCassette(dir string) (*Cassette, error) {
+	data, err := os.ReadFile(filepath.Join(dir, CassetteFile))
+	if err != nil {
+		return nil, err
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("invalid cassette: %w", err)
+	}
+	return &cassette, nil
+}
+
+// save writes the cassette to dir
+func (c *Cassette) save(dir string) error {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(dir, CassetteFile), data, 0600)
+}
+
+// Recorder is an http.RoundTripper that saves every interaction to a
+// cassette, with session cookies, CSRF tokens and passwords scrubbed
+type Recorder struct {
+	base http.RoundTripper
+	dir  string
+
+	mu       sync.Mutex
+	cassette *Cassette
+}
+
+// NewRecorder records interactions made through base into dir. Interactions
+// already recorded in dir are kept, so several commands can be captured in
+// one cassette.
+func NewRecorder(base http.RoundTripper, dir string) (*Recorder, error) {
+	cassette, err := LoadCassette(dir)
+	if errors.Is(err, os.ErrNotExist) {
+		cassette, err = &Cassette{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	return &Recorder{base: base, dir: dir, cassette: cassette}, nil
+}
+
+// RoundTrip implements http.RoundTripper
+func (r *Recorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	resp, err := r.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+
+	reqHeaders, reqSecrets := RedactHeaders(req.Header)
+	respHeaders, respSecrets := RedactHeaders(resp.Header)
+	secrets := append(reqSecrets, respSecrets...)
+
+	interaction := Interaction{
+		Request: RecordedRequest{
+			Method:  req.Method,
+			URL:     RedactString(req.URL.String(), secrets),
+			Headers: reqHeaders,
+			Body:    string(RedactBody(reqBody, secrets)),
+		},
+		Response: RecordedResponse{
+			Status:  resp.StatusCode,
+			Headers: respHeaders,
+			Body:    string(RedactBody(respBody, secrets)),
+		},
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	if err := r.cassette.save(r.dir); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to save cassette: %w", err)
+	}
+
+	return resp, nil
+}
+
+// Replayer is an http.RoundTripper that answers requests from a cassette
+// without touching the network
+type Replayer struct {
+	mu       sync.Mutex
+	cassette *Cassette
+	used     []bool
+}
+
+// NewReplayer replays the cassette saved in dir
+func NewReplayer(dir string) (*Replayer, error) {
+	cassette, err := LoadCassette(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load cassette: %w", err)
+	}
+
+	return &Replayer{
+		cassette: cassette,
+		used:     make([]bool, len(cassette.Interactions)),
+	}, nil
+}
+
+// RoundTrip implements http.RoundTripper. Requests are matched by method,
+// path and query; interactions are replayed in recorded order, and the last
+// match is repeated once they have all been used.
+func (r *Replayer) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Body != nil {
+		req.Body.Close()
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	match := -1
+	for i, interaction := range r.cassette.Interactions {
+		if !sameRequest(interaction.Request, req) {
+			continue
+		}
+		match = i
+		if !r.used[i] {
+			break
+		}
+	}
+	if match < 0 {
+		return nil, fmt.Errorf("no recorded interaction for %s %s", req.Method, req.URL.RequestURI())
+	}
+	r.used[match] = true
+
+	recorded := r.cassette.Interactions[match].Response
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.Status, http.StatusText(recorded.Status)),
+		StatusCode:    recorded.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        recorded.Headers.Clone(),
+		Body:          io.NopCloser(bytes.NewBufferString(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       req,
+	}, nil
+}
+
+// sameRequest reports whether a recorded request matches req
+func sameRequest(recorded RecordedRequest, req *http.Request) bool {
+	if recorded.Method != req.Method {
+		return false
+	}
+
+	u, err := req.URL.Parse(recorded.URL)
+	if err != nil {
+		return false
+	}
+	return u.Path == req.URL.Path && u.RawQuery == req.URL.RawQuery
+}
+
+// readRequestBody reads the request body and replaces it so it can still
+// be sent
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
@@ -0,0 +1,245 @@
+package transport
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// HAR is an HTTP Archive (HAR 1.2) document, as loaded by browser devtools
+type HAR struct {
+	Log HARLog `json:"log"`
+}
+
+// HARLog is the root object of a HAR document
+type HARLog struct {
+	Version string     `json:"version"`
+	Creator HARCreator `json:"creator"`
+	Entries []HAREntry `json:"entries"`
+}
+
+// HARCreator names the application that wrote the archive
+type HARCreator struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+}
+
+// HAREntry is a single request and response
+type HAREntry struct {
+	StartedDateTime time.Time   `json:"startedDateTime"`
+	Time            float64     `json:"time"`
+	Request         HARRequest  `json:"request"`
+	Response        HARResponse `json:"response"`
+	Cache           struct{}    `json:"cache"`
+	Timings         HARTimings  `json:"timings"`
+}
+
+// HARRequest describes the request of an entry
+type HARRequest struct {
+	Method      string         `json:"method"`
+	URL         string         `json:"url"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	QueryString []HARNameValue `json:"queryString"`
+	PostData    *HARPostData   `json:"postData,omitempty"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARResponse describes the response of an entry
+type HARResponse struct {
+	Status      int            `json:"status"`
+	StatusText  string         `json:"statusText"`
+	HTTPVersion string         `json:"httpVersion"`
+	Cookies     []HARNameValue `json:"cookies"`
+	Headers     []HARNameValue `json:"headers"`
+	Content     HARContent     `json:"content"`
+	RedirectURL string         `json:"redirectURL"`
+	HeadersSize int            `json:"headersSize"`
+	BodySize    int            `json:"bodySize"`
+}
+
+// HARNameValue is a header, cookie or query parameter
+type HARNameValue struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// HARPostData is the body of a request
+type HARPostData struct {
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARContent is the body of a response
+type HARContent struct {
+	Size     int    `json:"size"`
+	MimeType string `json:"mimeType"`
+	Text     string `json:"text"`
+}
+
+// HARTimings breaks down the time spent on an entry, in milliseconds
+type HARTimings struct {
+	Send    float64 `json:"send"`
+	Wait    float64 `json:"wait"`
+	Receive float64 `json:"receive"`
+}
+
+// HARRecorder is an http.RoundTripper that writes every interaction to a
+// HAR file, with session cookies, CSRF tokens and passwords redacted
+type HARRecorder struct {
+	base http.RoundTripper
+	path string
+
+	mu  sync.Mutex
+	har HAR
+}
+
+// NewHARRecorder records requests made through base to the HAR file at
+// path. The file is rewritten after each request so it is complete even if
+// the command is interrupted.
+func NewHARRecorder(base http.RoundTripper, path, creatorVersion string) *HARRecorder {
+	return &HARRecorder{
+		base: base,
+		path: path,
+		har: HAR{Log: HARLog{
+			Version: "1.2",
+			Creator: HARCreator{Name: "mathemcli", Version: creatorVersion},
+			Entries: []HAREntry{},
+		}},
+	}
+}
+
+// RoundTrip implements http.RoundTripper
+func (h *HARRecorder) RoundTrip(req *http.Request) (*http.Response, error) {
+	reqBody, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+
+	start := time.Now()
+	resp, err := h.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	wait := time.Since(start)
+
+	respBody, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(respBody))
+	receive := time.Since(start) - wait
+
+	reqHeaders, reqSecrets := RedactHeaders(req.Header)
+	respHeaders, respSecrets := RedactHeaders(resp.Header)
+	secrets := append(reqSecrets, respSecrets...)
+
+	entry := HAREntry{
+		StartedDateTime: start,
+		Time:            milliseconds(wait + receive),
+		Request: HARRequest{
+			Method:      req.Method,
+			URL:         req.URL.String(),
+			HTTPVersion: "HTTP/1.1",
+			Cookies:     harCookies(reqHeaders, "Cookie"),
+			Headers:     harHeaders(reqHeaders),
+			QueryString: harQuery(req),
+			HeadersSize: -1,
+			BodySize:    len(reqBody),
+		},
+		Response: HARResponse{
+			Status:      resp.StatusCode,
+			StatusText:  http.StatusText(resp.StatusCode),
+			HTTPVersion: resp.Proto,
+			Cookies:     harCookies(respHeaders, "Set-Cookie"),
+			Headers:     harHeaders(respHeaders),
+			Content: HARContent{
+				Size:     len(respBody),
+				MimeType: resp.Header.Get("Content-Type"),
+				Text:     string(RedactBody(respBody, secrets)),
+			},
+			RedirectURL: resp.Header.Get("Location"),
+			HeadersSize: -1,
+			BodySize:    len(respBody),
+		},
+		Timings: HARTimings{
+			Send:    0,
+			Wait:    milliseconds(wait),
+			Receive: milliseconds(receive),
+		},
+	}
+	if reqBody != nil {
+		entry.Request.PostData = &HARPostData{
+			MimeType: req.Header.Get("Content-Type"),
+			Text:     string(RedactBody(reqBody, secrets)),
+		}
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.har.Log.Entries = append(h.har.Log.Entries, entry)
+	if err := h.save(); err != nil {
+		resp.Body.Close()
+		return nil, fmt.Errorf("failed to write HAR file: %w", err)
+	}
+
+	return resp, nil
+}
+
+// save writes the archive to disk. h.mu must be held.
+func (h *HARRecorder) save() error {
+	data, err := json.MarshalIndent(h.har, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(h.path, data, 0600)
+}
+
+func harHeaders(h http.Header) []HARNameValue {
+	values := []HARNameValue{}
+	for name, vs := range h {
+		for _, v := range vs {
+			values = append(values, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return values
+}
+
+// harCookies parses cookies from already redacted headers
+func harCookies(h http.Header, header string) []HARNameValue {
+	var cookies []*http.Cookie
+	if header == "Set-Cookie" {
+		cookies = (&http.Response{Header: h}).Cookies()
+	} else {
+		cookies = (&http.Request{Header: h}).Cookies()
+	}
+
+	values := []HARNameValue{}
+	for _, c := range cookies {
+		values = append(values, HARNameValue{Name: c.Name, Value: c.Value})
+	}
+	return values
+}
+
+func harQuery(req *http.Request) []HARNameValue {
+	values := []HARNameValue{}
+	for name, vs := range req.URL.Query() {
+		for _, v := range vs {
+			values = append(values, HARNameValue{Name: name, Value: v})
+		}
+	}
+	return values
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,112 @@
+// Package transport provides http.RoundTrippers for debugging the client:
+// recording and replaying interactions, tracing requests and HAR export.
+package transport
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+// Redacted replaces secrets in saved or logged traffic
+const Redacted = "REDACTED"
+
+// SensitiveCookies are the cookies that carry the login session
+var SensitiveCookies = []string{"sessionid", "csrftoken"}
+
+// sensitiveFields are JSON body fields that must never be saved
+var sensitiveFields = []string{"password"}
+
+func isSensitiveCookie(name string) bool {
+	for _, c := range SensitiveCookies {
+		if strings.EqualFold(name, c) {
+			return true
+		}
+	}
+	return false
+}
+
+// RedactHeaders returns a copy of h with session cookies and CSRF tokens
+// replaced by Redacted. The secret values found are returned so they can be
+// scrubbed from bodies too.
+func RedactHeaders(h http.Header) (http.Header, []string) {
+	out := h.Clone()
+	var secrets []string
+
+	if cookies := out.Values("Cookie"); len(cookies) > 0 {
+		var redacted []string
+		for _, line := range cookies {
+			var parts []string
+			for _, pair := range strings.Split(line, ";") {
+				name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if ok && isSensitiveCookie(name) {
+					secrets = append(secrets, value)
+					value = Redacted
+				}
+				if ok {
+					parts = append(parts, name+"="+value)
+				} else if name != "" {
+					parts = append(parts, name)
+				}
+			}
+			redacted = append(redacted, strings.Join(parts, "; "))
+		}
+		out["Cookie"] = redacted
+	}
+
+	if setCookies := out.Values("Set-Cookie"); len(setCookies) > 0 {
+		var redacted []string
+		for _, line := range setCookies {
+			pair, attrs, _ := strings.Cut(line, ";")
+			name, value, ok := strings.Cut(strings.TrimSpace(pair), "=")
+			if ok && isSensitiveCookie(name) {
+				secrets = append(secrets, value)
+				line = name + "=" + Redacted
+				if attrs != "" {
+					line += ";" + attrs
+				}
+			}
+			redacted = append(redacted, line)
+		}
+		out["Set-Cookie"] = redacted
+	}
+
+	if token := out.Get("X-CSRFToken"); token != "" {
+		secrets = append(secrets, token)
+		out.Set("X-CSRFToken", Redacted)
+	}
+
+	return out, secrets
+}
+
+// RedactBody replaces sensitive JSON fields and any of the given secrets in
+// a request or response body
+func RedactBody(body []byte, secrets []string) []byte {
+	var fields map[string]any
+	if json.Unmarshal(body, &fields) == nil {
+		changed := false
+		for _, f := range sensitiveFields {
+			if _, ok := fields[f]; ok {
+				fields[f] = Redacted
+				changed = true
+			}
+		}
+		if changed {
+			if redacted, err := json.Marshal(fields); err == nil {
+				body = redacted
+			}
+		}
+	}
+
+	return []byte(RedactString(string(body), secrets))
+}
+
+// RedactString replaces every occurrence of the secrets in s
+func RedactString(s string, secrets []string) string {
+	for _, secret := range secrets {
+		if secret != "" && secret != Redacted {
+			s = strings.ReplaceAll(s, secret, Redacted)
+		}
+	}
+	return s
+}
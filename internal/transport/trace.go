@@ -0,0 +1,59 @@
+package transport
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sync"
+	"time"
+)
+
+// Tracer is an http.RoundTripper that logs every request and response to a
+// writer, with session cookies redacted
+type Tracer struct {
+	base http.RoundTripper
+
+	mu  sync.Mutex
+	out io.Writer
+}
+
+// NewTracer logs requests made through base to out
+func NewTracer(base http.RoundTripper, out io.Writer) *Tracer {
+	return &Tracer{base: base, out: out}
+}
+
+// RoundTrip implements http.RoundTripper
+func (t *Tracer) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+	latency := time.Since(start).Round(time.Millisecond)
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	fmt.Fprintf(t.out, "> %s %s\n", req.Method, req.URL)
+	writeHeaders(t.out, "> ", req.Header)
+
+	if err != nil {
+		fmt.Fprintf(t.out, "< error after %s: %v\n\n", latency, err)
+		return nil, err
+	}
+
+	fmt.Fprintf(t.out, "< %s (%s)\n", resp.Status, latency)
+	writeHeaders(t.out, "< ", resp.Header)
+	fmt.Fprintln(t.out)
+
+	return resp, nil
+}
+
+// writeHeaders prints redacted headers in a stable order
+func writeHeaders(w io.Writer, prefix string, h http.Header) {
+	redacted, _ := RedactHeaders(h)
+	for _, name := range slices.Sorted(maps.Keys(redacted)) {
+		for _, value := range redacted[name] {
+			fmt.Fprintf(w, "%s%s: %s\n", prefix, name, value)
+		}
+	}
+}